@@ -0,0 +1,939 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/config"
+	"github.com/faceless-saint/m3/lib/java"
+	"github.com/faceless-saint/m3/lib/loader"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/output"
+	"github.com/faceless-saint/m3/lib/pack"
+	"github.com/faceless-saint/m3/lib/plan"
+	"github.com/faceless-saint/m3/lib/spec"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// command values represent the subcommands of the installer.
+type command struct {
+	Name  string
+	Usage string
+	// Spec indicates that the command operates on the modpack spec. Such
+	// commands are run from within the target directory.
+	Spec bool
+	// Modify indicates that the command may modify the target directory.
+	Modify bool
+	Run    func(conf *config.Config, s *spec.Spec) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"install", "Install the modpack (default)", true, true, runInstall},
+		{"update", "Update mods and configs, then prune obsolete mods", true, true, runUpdate},
+		{"plan", "Print the changes an install would make", true, false, runPlan},
+		{"verify", "Verify installed mods without downloading", true, false, runVerify},
+		{"diff", "Show differences between the spec and installed mods", true, false, runDiff},
+		{"prune", "Delete all disabled mods", true, true, runPrune},
+		{"lock", "Write the modpack lockfile", true, false, runLock},
+		{"features", "List optional features, or select them with -enable/-disable", true, false, runFeatures},
+		{"deps", "Check the dependencies declared by the installed mods", true, false, runDeps},
+		{"inspect", "Show the mods in a jar or a directory of jars", false, false, runInspect},
+		{"rollback", "Revert an interrupted or failed installation", false, false, runRollback},
+		{"spec render", "Print the fully resolved spec", false, false, runSpecRender},
+		{"spec validate", "Check the spec for problems", false, false, runSpecValidate},
+		{"spec convert", "Convert the spec to JSON, YAML or TOML", false, false, runSpecConvert},
+		{"spec init", "Create a new spec, from existing jars with -from", false, false, runSpecInit},
+		{"import curseforge", "Create a new spec from a CurseForge modpack zip", false, false, runImportCurseForge},
+		{"export curseforge", "Write the modpack as a CurseForge modpack zip", true, false, runExportCurseForge},
+		{"import modrinth", "Create a new spec from a Modrinth modpack (.mrpack)", false, false, runImportModrinth},
+		{"export modrinth", "Write the modpack as a Modrinth modpack (.mrpack)", true, false, runExportModrinth},
+		{"version", "Print the version", false, false, runVersion},
+		{"help", "Print this help", false, false, runHelp},
+	}
+}
+
+// findCommand returns the command with the given name, or nil.
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// hasSubcommands returns true iff commands named "<name> <subcommand>"
+// exist.
+func hasSubcommands(name string) bool {
+	for _, el := range commands {
+		if strings.HasPrefix(el.Name, name+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// usage prints the list of available commands.
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [options]\n\ncommands:\n",
+		filepath.Base(os.Args[0]))
+	for _, el := range commands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", el.Name, el.Usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '<command> -h' for a list of options.\n")
+}
+
+// runInstall installs the mods, configs and (optionally) the loader.
+func runInstall(conf *config.Config, s *spec.Spec) error {
+	install := conf.Install.Client || conf.Install.Server
+	if err := sync(conf, s, install, false); err != nil || conf.DryRun {
+		return err
+	}
+	fmt.Print("Installation complete!\n")
+	return nil
+}
+
+// runUpdate updates the mods and configs without touching the loader,
+// deleting the mods that are disabled rather than keeping them.
+func runUpdate(conf *config.Config, s *spec.Spec) error {
+	if err := sync(conf, s, false, true); err != nil || conf.DryRun {
+		return err
+	}
+	fmt.Print("Update complete!\n")
+	return nil
+}
+
+// runPlan prints the changes an installation would make.
+func runPlan(conf *config.Config, s *spec.Spec) error {
+	p, err := s.Plan(conf.Env.ModDir, conf.Install.Client || conf.Install.Server)
+	if err != nil {
+		return err
+	}
+	return printPlan(conf, p)
+}
+
+// runVerify checks the installed mods against the spec.
+func runVerify(conf *config.Config, s *spec.Spec) error {
+	status, err := s.Mods.Status(conf.Env.ModDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d of %d mods valid.\n", len(status.Valid), len(s.Mods.Items))
+	printNames(os.Stderr, "missing", status.Missing)
+	printNames(os.Stderr, "invalid", status.Invalid)
+	printNames(os.Stderr, "disabled", status.Disabled)
+	printNames(os.Stdout, "unexpected", status.Extra)
+
+	if s.Loader != nil {
+		if _, err := os.Stat(s.Loader.Filename()); err == nil {
+			ok, err := net.CheckFile(s.Loader.Filename(), s.Loader.Checksum(), s.Loader.Hash())
+			if err != nil {
+				return err
+			} else if !ok {
+				fmt.Fprintf(os.Stderr, "\t%s - invalid\n", s.Loader.Filename())
+				return fmt.Errorf("error: verification failed")
+			}
+		}
+	}
+	if v, ok := s.Loader.(loader.Verifier); ok && conf.Install.Server {
+		if err := v.Verify(); err != nil {
+			fmt.Fprintf(os.Stderr, "\t%v\n", err)
+			return fmt.Errorf("error: verification failed")
+		}
+	}
+	if !status.Ok() {
+		return fmt.Errorf("error: verification failed")
+	}
+	fmt.Print("Verification passed.\n")
+	return nil
+}
+
+// runDiff prints the changes an installation would make to the mods.
+func runDiff(conf *config.Config, s *spec.Spec) error {
+	status, err := s.Mods.Status(conf.Env.ModDir)
+	if err != nil {
+		return err
+	}
+	changes := 0
+	for _, el := range status.Missing {
+		fmt.Printf("+ %s\n", el)
+		changes++
+	}
+	for _, el := range status.Disabled {
+		fmt.Printf("+ %s (enable)\n", el)
+		changes++
+	}
+	for _, el := range status.Invalid {
+		fmt.Printf("~ %s\n", el)
+		changes++
+	}
+	for _, el := range status.Extra {
+		fmt.Printf("- %s\n", el)
+		changes++
+	}
+	if changes == 0 {
+		fmt.Print("No changes.\n")
+	}
+	return nil
+}
+
+// runPrune deletes all disabled mods.
+func runPrune(conf *config.Config, s *spec.Spec) error {
+	return s.Mods.Prune(conf.Env.ModDir)
+}
+
+// runLock writes the lockfile for the spec. Mods and the loader installer
+// that are not installed locally are downloaded to a temporary directory
+// to compute their checksums.
+func runLock(conf *config.Config, s *spec.Spec) error {
+	cache, err := ioutil.TempDir("", "m3-lock")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(cache)
+
+	p, err := s.Mods.Plan(conf.Env.ModDir)
+	if err != nil {
+		return err
+	}
+	err = stage(conf, "mods", cache, len(s.Mods.Items),
+		pending(s.Mods.Items, conf.Env.ModDir, p))
+	if err != nil {
+		return err
+	}
+	if s.Loader != nil {
+		if p, err = s.Loader.Plan(); err != nil {
+			return err
+		}
+		if p.Count(plan.Download) > 0 {
+			fmt.Printf("Downloading %s installer... ", s.Loader.Name())
+			if err := fetch(s.Loader, filepath.Join(cache, s.Loader.Filename())); err != nil {
+				return err
+			}
+			fmt.Print("Done.\n")
+		}
+	}
+
+	lock, err := s.Lock(conf.Env.ModDir, cache)
+	if err != nil {
+		return err
+	}
+	if conf.Resolve {
+		if err := resolveDependencies(conf, s, lock, cache); err != nil {
+			return err
+		}
+	}
+	if err := lock.WriteFile(conf.LockFile()); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", conf.LockFile())
+	return nil
+}
+
+// runDeps checks the dependencies declared by the metadata of the installed
+// mods, failing if a required mod is missing, a mod is installed in a
+// version outside the required range, or incompatible mods are installed.
+func runDeps(conf *config.Config, s *spec.Spec) error {
+	mods, err := mod.ReadMetadataDir(conf.Env.ModDir)
+	if err != nil {
+		return err
+	}
+	issues := s.CheckDependencies(mods, conf.Install.Side())
+	if conf.Json {
+		type entry struct {
+			Kind, Mod, File, Dependency, Versions, Found, Message string
+			Fatal                                                 bool
+		}
+		list := []entry{}
+		for _, el := range issues {
+			list = append(list, entry{el.Kind, el.Mod.Id, filepath.Base(el.Mod.File),
+				el.Dependency.Id, el.Dependency.Range(), el.Found, el.String(), el.Fatal()})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(list); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Checked %d mods.\n", len(mods))
+		printIssues(os.Stdout, issues)
+	}
+	for _, el := range issues {
+		if el.Fatal() {
+			return fmt.Errorf("error: dependency check failed")
+		}
+	}
+	if !conf.Json {
+		fmt.Print("Dependencies satisfied.\n")
+	}
+	return nil
+}
+
+// runInspect prints the mods declared by the metadata of a jar, or of every
+// jar in a directory, as a table or in JSON mode as JSON.
+func runInspect(conf *config.Config, s *spec.Spec) error {
+	if len(conf.Args) != 1 {
+		return fmt.Errorf("error: usage: inspect [options] <jar|dir>")
+	}
+	mods, err := mod.Inspect(conf.Args[0])
+	if err != nil {
+		return err
+	}
+	if conf.Json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		return enc.Encode(mods)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprint(w, "FILE\tID\tNAME\tVERSION\tLOADER\tSIDE\tMINECRAFT\tLOADER VERSION\n")
+	for _, el := range mods {
+		fields := []string{filepath.Base(el.File), el.Id, el.Name, el.Version,
+			el.Loader, el.Side, el.Minecraft, el.LoaderVersion}
+		for i, field := range fields {
+			if field == "" {
+				fields[i] = "-"
+			}
+		}
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+	}
+	return w.Flush()
+}
+
+// runFeatures lists the optional features of the spec and whether they
+// are selected.
+func runFeatures(conf *config.Config, s *spec.Spec) error {
+	if len(s.Mods.Features) == 0 {
+		fmt.Print("No optional features.\n")
+		return nil
+	}
+	for _, el := range s.Mods.Features {
+		mark := " "
+		if s.Mods.Selection.Selected(el) {
+			mark = "x"
+		}
+		fmt.Printf("[%s] %s - %s\n", mark, el.Name, strings.Join(el.Mods, ", "))
+	}
+	return nil
+}
+
+// runRollback reverts an interrupted or failed installation.
+func runRollback(conf *config.Config, s *spec.Spec) error {
+	dir := filepath.Join(conf.Env.TargetDir, plan.StateDir)
+	if err := plan.Rollback(dir); err != nil {
+		return err
+	}
+	fmt.Print("Rollback complete.\n")
+	return nil
+}
+
+// runSpecRender prints the spec with all extended specs merged into it.
+func runSpecRender(conf *config.Config, s *spec.Spec) error {
+	// Keep standard output a valid spec
+	spec.Log = os.Stderr
+	raw, err := conf.GetRaw()
+	if err != nil {
+		return err
+	}
+	data, err := raw.Render()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// runSpecValidate checks the spec, and the specs it extends, for problems
+// without downloading any mods. Problems are printed as JSON in JSON mode.
+func runSpecValidate(conf *config.Config, s *spec.Spec) error {
+	spec.Log = os.Stderr
+	_, err := conf.GetRaw()
+	if verr, ok := err.(*spec.ValidationError); ok && conf.Json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(verr); err != nil {
+			return err
+		}
+		return fmt.Errorf("error: spec validation failed")
+	} else if err != nil {
+		return err
+	}
+	if conf.Json {
+		fmt.Print("{\"Problems\": []}\n")
+	} else {
+		fmt.Print("Spec is valid.\n")
+	}
+	return nil
+}
+
+// runSpecConvert translates the spec, as written, into another format. The
+// target is a file, whose extension selects the format, or just the name
+// of a format to print the result.
+func runSpecConvert(conf *config.Config, s *spec.Spec) error {
+	if len(conf.Args) != 1 {
+		return fmt.Errorf("error: usage: spec convert [options] <file|%s>",
+			strings.Join(spec.Formats, "|"))
+	}
+	source := conf.Remote
+	if source == "" {
+		source = conf.Local
+	}
+	data, from, err := spec.Load(source)
+	if err != nil {
+		return err
+	}
+	target, to := conf.Args[0], conf.Args[0]
+	for _, el := range spec.Formats {
+		if target == el {
+			target = ""
+		}
+	}
+	if target != "" {
+		if to = spec.FormatOf(target); to == "" {
+			return fmt.Errorf("error: unknown spec format of %s - use a .json, .yaml or .toml file", target)
+		}
+		if _, err := os.Stat(target); err == nil {
+			return fmt.Errorf("error: %s already exists", target)
+		}
+	}
+	data, err = spec.Convert(data, from, to)
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := ioutil.WriteFile(target, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Converted %s to %s\n", source, target)
+	return nil
+}
+
+// runSpecInit creates a new spec file. With -from, every jar in the given
+// directory is identified through Modrinth and CurseForge and added to it.
+// The format of the spec is chosen by its file extension.
+func runSpecInit(conf *config.Config, s *spec.Spec) error {
+	if err := checkNewSpec(conf); err != nil {
+		return err
+	}
+	mod.CurseApiKey = conf.CurseApiKey
+	raw := &spec.Raw{SpecVersion: spec.SpecVersion}
+	if conf.From != "" {
+		var err error
+		if raw, err = spec.Init(conf.From, filepath.Dir(conf.Local)); err != nil {
+			return err
+		}
+	}
+	return writeSpec(conf, raw)
+}
+
+// runImportCurseForge creates a new spec from a CurseForge modpack zip. The
+// overrides of the pack are extracted next to the spec.
+func runImportCurseForge(conf *config.Config, s *spec.Spec) error {
+	if len(conf.Args) != 1 {
+		return fmt.Errorf("error: usage: import curseforge [options] <pack.zip>")
+	}
+	if err := checkNewSpec(conf); err != nil {
+		return err
+	}
+	mod.CurseApiKey = conf.CurseApiKey
+	raw, err := pack.ImportCurseForge(conf.Args[0], filepath.Dir(conf.Local))
+	if err != nil {
+		return err
+	}
+	return writeSpec(conf, raw)
+}
+
+// runExportCurseForge writes the modpack as a CurseForge modpack zip, named
+// after the modpack unless a file is given. Bundled mods and configs that
+// are not installed are downloaded to a temporary directory first.
+func runExportCurseForge(conf *config.Config, s *spec.Spec) error {
+	if len(conf.Args) > 1 {
+		return fmt.Errorf("error: usage: export curseforge [options] [pack.zip]")
+	}
+	cache, err := ioutil.TempDir("", "m3-export")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(cache)
+
+	bundled := net.Downloadables{}
+	for _, el := range s.Mods.Items {
+		if _, ok := el.(*mod.CurseMod); !ok && mod.OnSide(el, mod.SideClient) {
+			bundled = append(bundled, el)
+		}
+	}
+	src, err := stageBundled(conf, s, bundled, cache)
+	if err != nil {
+		return err
+	}
+	file := exportFile(conf, s, ".zip")
+	if err := pack.ExportCurseForge(s, file, src); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", file)
+	return nil
+}
+
+// runImportModrinth creates a new spec from a Modrinth modpack. The
+// overrides of the pack are extracted next to the spec.
+func runImportModrinth(conf *config.Config, s *spec.Spec) error {
+	if len(conf.Args) != 1 {
+		return fmt.Errorf("error: usage: import modrinth [options] <pack.mrpack>")
+	}
+	if err := checkNewSpec(conf); err != nil {
+		return err
+	}
+	raw, err := pack.ImportModrinth(conf.Args[0], filepath.Dir(conf.Local))
+	if err != nil {
+		return err
+	}
+	return writeSpec(conf, raw)
+}
+
+// runExportModrinth writes the modpack as a Modrinth modpack, named after
+// the modpack unless a file is given. Mods that are not installed are
+// downloaded to a temporary directory first, to bundle them or to hash
+// them for the index.
+func runExportModrinth(conf *config.Config, s *spec.Spec) error {
+	if len(conf.Args) > 1 {
+		return fmt.Errorf("error: usage: export modrinth [options] [pack.mrpack]")
+	}
+	cache, err := ioutil.TempDir("", "m3-export")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(cache)
+
+	mods := append(net.Downloadables{}, s.Mods.Items...)
+	for _, el := range s.Mods.Inactive {
+		if pack.ModrinthHosted(el) {
+			mods = append(mods, el)
+		}
+	}
+	src, err := stageBundled(conf, s, mods, cache)
+	if err != nil {
+		return err
+	}
+	file := exportFile(conf, s, ".mrpack")
+	if err := pack.ExportModrinth(s, file, src); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", file)
+	return nil
+}
+
+// exportFile returns the file to export the modpack to: the command's
+// argument, or else a file with the given extension in the target
+// directory named after the modpack or the target directory.
+func exportFile(conf *config.Config, s *spec.Spec, ext string) string {
+	if len(conf.Args) == 1 {
+		return conf.Args[0]
+	} else if s.Name != "" {
+		return filepath.Join(conf.Env.TargetDir, s.Name+ext)
+	}
+	return filepath.Join(conf.Env.TargetDir, filepath.Base(conf.Env.TargetDir)+ext)
+}
+
+// stageBundled downloads the given mods, and the configs, into the cache
+// directory unless they are installed, returning where to find them.
+func stageBundled(conf *config.Config, s *spec.Spec, mods net.Downloadables, cache string) (*pack.Sources, error) {
+	src := &pack.Sources{
+		Mods:    []string{filepath.Join(cache, "mods"), conf.Env.ModDir},
+		Configs: []string{filepath.Join(cache, "config"), spec.ConfigDir},
+	}
+	p, err := s.Mods.Plan(conf.Env.ModDir)
+	if err != nil {
+		return nil, err
+	}
+	// Inactive mods are not planned, and their jars may be disabled
+	missing := pending(mods, conf.Env.ModDir, p)
+	planned := map[net.Downloadable]*struct{}{}
+	for _, el := range missing {
+		planned[el] = new(struct{})
+	}
+	for _, el := range mods {
+		_, err := os.Stat(filepath.Join(conf.Env.ModDir, el.Filename()))
+		if _, ok := planned[el]; !ok && err != nil {
+			missing = append(missing, el)
+		}
+	}
+	if err := stage(conf, "mods", src.Mods[0], len(mods), missing); err != nil {
+		return nil, err
+	}
+	if p, err = s.Config.Plan(); err != nil {
+		return nil, err
+	}
+	err = stage(conf, "configs", src.Configs[0], len(s.Config.Items),
+		pending(s.Config.Items, spec.ConfigDir, p))
+	return src, err
+}
+
+// checkNewSpec checks that the local spec can be created.
+func checkNewSpec(conf *config.Config) error {
+	if conf.Remote != "" {
+		return fmt.Errorf("error: cannot create a remote spec")
+	}
+	if _, err := os.Stat(conf.Local); err == nil {
+		return fmt.Errorf("error: %s already exists", conf.Local)
+	}
+	if spec.FormatOf(conf.Local) == "" {
+		return fmt.Errorf("error: unknown spec format of %s - use a .json, .yaml or .toml file", conf.Local)
+	}
+	return nil
+}
+
+// writeSpec writes the Raw spec to the local spec file, in the format of
+// its extension.
+func writeSpec(conf *config.Config, raw *spec.Raw) error {
+	data, err := raw.Render()
+	if err != nil {
+		return err
+	}
+	if data, err = spec.Convert(data, spec.FormatJSON, spec.FormatOf(conf.Local)); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(conf.Local, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", conf.Local)
+	return nil
+}
+
+// runVersion prints the program version.
+func runVersion(conf *config.Config, s *spec.Spec) error {
+	fmt.Printf("m3 %s\n", version)
+	return nil
+}
+
+// runHelp prints the program usage.
+func runHelp(conf *config.Config, s *spec.Spec) error {
+	usage()
+	return nil
+}
+
+// sync computes the installation plan and applies it as a transaction.
+// All downloads are staged and verified before any installed file is
+// changed, and every change is rolled back if any step fails. The loader
+// is only installed if 'install' is true, and disabled mods are deleted
+// if 'prune' is true. In dry-run mode the plan is only printed.
+func sync(conf *config.Config, s *spec.Spec, install, prune bool) error {
+	p, err := s.Plan(conf.Env.ModDir, install)
+	if err != nil {
+		return err
+	}
+	if prune {
+		if p.Mods, err = s.Mods.PlanPrune(conf.Env.ModDir, p.Mods); err != nil {
+			return err
+		}
+	}
+	if conf.DryRun {
+		return printPlan(conf, p)
+	} else if conf.Verbose {
+		p.Print(os.Stdout)
+	}
+	opts, err := options(conf, s)
+	if err != nil {
+		return err
+	}
+
+	txn, err := plan.Begin(plan.StateDir)
+	if err != nil {
+		return err
+	}
+	if err := apply(conf, s, p, opts, txn); err != nil {
+		fmt.Fprint(os.Stderr, "Installation failed - rolling back changes...\n")
+		if rerr := txn.Rollback(); rerr != nil {
+			return fmt.Errorf("%v\nerror: rollback failed: %v", err, rerr)
+		}
+		return err
+	}
+	return txn.Commit()
+}
+
+// apply stages all downloads of the plan, then applies the plan within
+// the transaction and runs the loader installation if needed. The files
+// changed by the loader installation are preserved by the transaction.
+func apply(conf *config.Config, s *spec.Spec, p *spec.Plan, opts *loader.Options, txn *plan.Transaction) error {
+	// Download and verify mods and configs
+	err := stage(conf, "mods", txn.StagingPath(conf.Env.ModDir), len(s.Mods.Items),
+		pending(s.Mods.Items, conf.Env.ModDir, p.Mods))
+	if err != nil {
+		return err
+	}
+	err = stage(conf, "configs", txn.StagingPath(spec.ConfigDir), len(s.Config.Items),
+		pending(s.Config.Items, spec.ConfigDir, p.Config))
+	if err != nil {
+		return err
+	}
+	if p.Loader.Count(plan.Download) > 0 {
+		// Download the loader intaller
+		fmt.Printf("Downloading %s installer... ", s.Loader.Name())
+		file := txn.StagingPath(s.Loader.Filename())
+		if err := fetch(s.Loader, file); err != nil {
+			return err
+		}
+		fmt.Print("Done.\n")
+	}
+
+	// Move staged files into place
+	if err := txn.Apply(p.Loader); err != nil {
+		return err
+	}
+	if err := txn.Apply(p.Mods); err != nil {
+		return err
+	}
+	if err := txn.Apply(p.Config); err != nil {
+		return err
+	}
+	// Report dependency problems before the game is started
+	checkDependencies(conf, s)
+
+	if s.Loader != nil && (conf.Install.Server || conf.Install.Client) {
+		// Install the loader server files or client profile
+		fmt.Printf("Installing %s...\n", s.Loader)
+		opts.Preserve = txn.Preserve
+		if err := s.Loader.Install(opts); err != nil {
+			return err
+		}
+		fmt.Print("Done.\n")
+	}
+	return nil
+}
+
+// options returns the loader installation options for the Config. If the
+// loader is installed and either runs Java or the spec declares a Java
+// version, a matching Java runtime is selected, failing early if none is
+// found.
+func options(conf *config.Config, s *spec.Spec) (*loader.Options, error) {
+	profile := conf.Install.Profile
+	if profile == "" {
+		profile = filepath.Base(conf.Env.TargetDir)
+	}
+	opts := loader.Options{
+		Server:       conf.Install.Server,
+		Client:       conf.Install.Client,
+		MinecraftDir: conf.Env.MinecraftDir,
+		GameDir:      conf.Env.TargetDir,
+		Profile:      profile,
+		Concurrency:  conf.Env.Concurrency,
+		Verbose:      conf.Verbose,
+	}
+	if s.Loader == nil || !(opts.Server || opts.Client) {
+		return &opts, nil
+	}
+	if u, ok := s.Loader.(loader.JavaUser); s.Java == 0 && !(ok && u.UsesJava(&opts)) {
+		return &opts, nil
+	}
+	rt, err := java.Select(java.Find(), s.JavaVersion())
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Using %s\n", rt)
+	opts.Java = rt.Path
+	return &opts, nil
+}
+
+// checkDependencies prints the problems with the dependencies declared by
+// the installed mods as warnings.
+func checkDependencies(conf *config.Config, s *spec.Spec) {
+	mods, err := mod.ReadMetadataDir(conf.Env.ModDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not check mod dependencies: %v\n", err)
+		return
+	}
+	issues := s.CheckDependencies(mods, conf.Install.Side())
+	if len(issues) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d mod dependency problems found:\n", len(issues))
+		printIssues(os.Stderr, issues)
+	}
+}
+
+// resolveDependencies adds the missing required dependencies of the locked
+// mods to the lock, found on Modrinth or CurseForge for the spec's loader.
+// Resolved mods are checked in turn, until no more can be resolved. The
+// remaining problems are printed as warnings.
+func resolveDependencies(conf *config.Config, s *spec.Spec, lock *spec.Lock, cache string) error {
+	if s.Loader == nil {
+		return fmt.Errorf("error: resolving dependencies requires a loader")
+	}
+	// Each dependency is only attempted once: a resolved jar may still not
+	// provide it, such as a project matched by slug with another mod ID
+	attempted := map[string]*struct{}{}
+	for {
+		files := []string{}
+		for _, el := range lock.Mods {
+			file := filepath.Join(cache, el.Filename)
+			if _, err := os.Stat(file); err != nil {
+				file = filepath.Join(conf.Env.ModDir, el.Filename)
+			}
+			files = append(files, file)
+		}
+		mods, err := mod.ReadMetadataFiles(files)
+		if err != nil {
+			return err
+		}
+		issues := s.CheckDependencies(mods, "")
+		added := 0
+		for _, dep := range mod.MissingDependencies(issues) {
+			if _, ok := attempted[dep.Id]; ok {
+				continue
+			}
+			attempted[dep.Id] = new(struct{})
+			dl, err := mod.Resolve(dep, s.Loader.Name(), s.Loader.Minecraft())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Could not resolve %s: %v\n", dep.Id, err)
+				continue
+			}
+			file := filepath.Join(cache, dl.Filename())
+			if err := fetch(dl, file); err != nil {
+				return err
+			}
+			locked, err := spec.NewLockedFile(dl, file)
+			if err != nil {
+				return err
+			}
+			lock.Mods = append(lock.Mods, *locked)
+			fmt.Printf("Resolved %s: %s\n", dep.Id, dl.Filename())
+			added++
+		}
+		if added == 0 {
+			if len(issues) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %d mod dependency problems remain:\n", len(issues))
+				printIssues(os.Stderr, issues)
+			}
+			return nil
+		}
+	}
+}
+
+// selectFeatures applies the selection of optional features to the spec:
+// the persisted selection, updated with the -enable and -disable flags.
+// The updated selection is saved if 'persist' is true.
+func selectFeatures(conf *config.Config, s *spec.Spec, persist bool) error {
+	sel, err := mod.ReadSelection(conf.SelectionFile())
+	if err != nil {
+		return err
+	}
+	for _, list := range []struct {
+		Names    []string
+		Selected bool
+	}{{conf.Enable, true}, {conf.Disable, false}} {
+		for _, el := range list.Names {
+			if s.Mods.Feature(el) == nil {
+				return fmt.Errorf("error: unknown feature %q", el)
+			}
+			sel[el] = list.Selected
+		}
+	}
+	if persist && !conf.DryRun && len(conf.Enable)+len(conf.Disable) > 0 {
+		if err := sel.WriteFile(conf.SelectionFile()); err != nil {
+			return err
+		}
+	}
+	s.Mods.Select(sel)
+	return nil
+}
+
+// stage downloads (or copies, for local mods) the given files into the
+// directory 'staging' and verifies them. The total number of files is
+// used for progress output only.
+func stage(conf *config.Config, name, staging string, total int, items net.Downloadables) error {
+	remote, local := items.Split()
+	copied := local.CopyFiles(staging)
+	respch, count, err := remote.GetFiles(staging, conf.Env.Concurrency)
+	if err != nil {
+		return err
+	}
+	// Track download progress
+	tracker := output.DownloadTracker{Name: name, Channel: respch,
+		Interval: pb_timer, Count: count, Total: total, Dir: staging, Local: copied}
+	tracker.Log()
+	if failed := tracker.FailedLocal(); len(failed) > 0 {
+		return fmt.Errorf("error: %d %s failed to copy", len(failed), name)
+	}
+	if failed := tracker.Failed(); len(failed) > 0 {
+		return fmt.Errorf("error: %d %s failed to download", len(failed), name)
+	}
+	for _, el := range items {
+		if err := verify(el, filepath.Join(staging, el.Filename())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch downloads a single file to the given path and verifies it.
+func fetch(dl net.Downloadable, file string) error {
+	resp, err := net.GetFile(dl, file)
+	if err != nil {
+		return err
+	} else if resp != nil && resp.Error != nil {
+		return resp.Error
+	}
+	return verify(dl, file)
+}
+
+// verify checks a downloaded file against its reference checksum.
+func verify(dl net.Downloadable, file string) error {
+	ok, err := net.CheckFile(file, dl.Checksum(), dl.Hash())
+	if err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("error: checksum mismatch for %s", dl.Filename())
+	}
+	return nil
+}
+
+// pending returns the files the plan downloads into the directory 'dir'.
+func pending(items net.Downloadables, dir string, p plan.Plan) net.Downloadables {
+	paths := make(map[string]*struct{}, len(p))
+	for _, el := range p {
+		if el.Op == plan.Download || el.Op == plan.Overwrite {
+			paths[el.Path] = new(struct{})
+		}
+	}
+	list := net.Downloadables{}
+	for _, el := range items {
+		if _, ok := paths[filepath.Join(dir, el.Filename())]; ok {
+			list = append(list, el)
+		}
+	}
+	return list
+}
+
+// printPlan prints the plan in the configured output format.
+func printPlan(conf *config.Config, p *spec.Plan) error {
+	if conf.Json {
+		return p.WriteJSON(os.Stdout)
+	}
+	p.Print(os.Stdout)
+	return nil
+}
+
+// printIssues prints the dependency issues, marking those that are only
+// warnings.
+func printIssues(w *os.File, issues []mod.Issue) {
+	for _, el := range issues {
+		if el.Fatal() {
+			fmt.Fprintf(w, "\t%s\n", el)
+		} else {
+			fmt.Fprintf(w, "\t%s (warning)\n", el)
+		}
+	}
+}
+
+// printNames prints a labeled list of file names.
+func printNames(w *os.File, label string, names []string) {
+	for _, el := range names {
+		fmt.Fprintf(w, "\t%s - %s\n", el, label)
+	}
+}
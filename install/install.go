@@ -2,16 +2,22 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"github.com/faceless-saint/m3/lib/config"
-	"github.com/faceless-saint/m3/lib/output"
+	"github.com/faceless-saint/m3/lib/spec"
 	"golang.org/x/crypto/ssh/terminal"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // Set the download tracker interval
 const pb_timer = 200
 
+// Program version, overridden at build time
+var version = "dev"
+
 func main() {
 	// Pause at program completion, but only if session is a terminal.
 	if terminal.IsTerminal(int(os.Stdout.Fd())) {
@@ -19,71 +25,84 @@ func main() {
 		defer fmt.Print("Press [enter] to exit...\n")
 	}
 
-	// Parse configuration options
-	conf := config.Parse()
-
-	s, err := conf.GetSpec()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Return to original working directory before exiting
-	if original_path, err := os.Getwd(); err == nil {
-		defer os.Chdir(original_path)
-	} else {
+	if err := run(os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
-	// Create (if needed) and navigate to target directory
-	os.MkdirAll(conf.Env.TargetDir, 0755)
-	os.Chdir(conf.Env.TargetDir)
+}
 
-	// Start mod downloads
-	respch, count, err := s.Mods.Fetch(conf.Env.Concurrency, conf.Verbose)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+// run parses the command line arguments and runs the chosen command. If
+// no command is given, "install" is used.
+func run(args []string) error {
+	name := "install"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") && hasSubcommands(name) {
+		name, args = name+" "+args[0], args[1:]
+	}
+	cmd := findCommand(name)
+	if cmd == nil {
+		usage()
+		return fmt.Errorf("error: unknown command %q", name)
 	}
 
-	// Track mod download progress
-	modTracker := output.DownloadTracker{"mods", respch, nil, pb_timer, count, len(s.Mods.Items)}
-	modTracker.Log()
+	// Parse configuration options
+	prog := filepath.Base(os.Args[0])
+	conf, err := config.ParseArgs(prog+" "+cmd.Name, args)
+	if err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !cmd.Spec {
+		return cmd.Run(conf, nil)
+	}
+	if conf.Json {
+		// Keep standard output machine readable
+		spec.Log = os.Stderr
+	}
 
-	// Start config downloads
-	respch, count, err = s.Config.Fetch(conf.Env.Concurrency, conf.Verbose)
+	// Resolve paths before leaving the original directory
+	if conf.Env.TargetDir, err = filepath.Abs(conf.Env.TargetDir); err != nil {
+		return err
+	}
+	s, err := conf.GetSpec()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		return err
 	}
-
-	// Track config download progress
-	configTracker := output.DownloadTracker{"configs", respch, nil, pb_timer, count, len(s.Config.Items)}
-	configTracker.Log()
-
-	if conf.Install.Client || conf.Install.Server {
-		// Download the Forge intaller
-		fmt.Print("Downloading Forge installer... ")
-		_, err := s.Forge.Fetch(conf.Verbose)
+	if conf.Local != "" {
+		if conf.Local, err = filepath.Abs(conf.Local); err != nil {
+			return err
+		}
+	}
+	for i := range conf.Args {
+		if conf.Args[i], err = filepath.Abs(conf.Args[i]); err != nil {
+			return err
+		}
+	}
+	if cmd.Name != "lock" {
+		// The lockfile covers the mods of both sides and all features
+		s.Filter(conf.Install.Side())
+		err := selectFeatures(conf, s, cmd.Modify || cmd.Name == "features")
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			os.Exit(1)
+			return err
 		}
-		fmt.Print("Done.\n")
+	}
 
-		if conf.Install.Server {
-			// Install Forge server files
-			fmt.Print("Installing Forge server files... ")
-			if conf.Verbose {
-				fmt.Print("\n")
-			}
-			if err := s.Forge.Install(conf.Verbose); err != nil {
-				fmt.Fprintf(os.Stderr, "\n%v\n", err)
-				os.Exit(1)
-			} else {
-				fmt.Print("Done.\n")
-			}
-		}
+	// Return to original working directory before exiting
+	original_path, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	defer os.Chdir(original_path)
+
+	// Create (if needed) and navigate to target directory
+	if cmd.Modify {
+		os.MkdirAll(conf.Env.TargetDir, 0755)
+	}
+	if err := os.Chdir(conf.Env.TargetDir); err != nil {
+		return err
 	}
-	fmt.Print("Installation complete!\n")
+	return cmd.Run(conf, s)
 }
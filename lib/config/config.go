@@ -0,0 +1,407 @@
+/* Config is a library for assembling the runtime configuration of m3
+ * from command line flags, environment variables and the m3.conf file.
+ *
+ * Settings are resolved in the following order of precedence, from
+ * highest to lowest: command line flags, environment variables, the
+ * m3.conf file, and finally the built-in defaults.
+ */
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/git"
+	"github.com/faceless-saint/m3/lib/loader"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/spec"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Default values for all configurable settings.
+const (
+	DefaultConfFile    = "m3.conf"
+	DefaultSpecFile    = "modpack.json"
+	DefaultTargetDir   = "."
+	DefaultModDir      = "mods"
+	DefaultConcurrency = 3
+	// DefaultSelectionFile records the locally selected optional features.
+	DefaultSelectionFile = "modpack.selection.json"
+)
+
+// DefaultSpecFiles are the local spec files used, in order, if the
+// default spec file does not exist.
+var DefaultSpecFiles = []string{"modpack.yaml", "modpack.yml", "modpack.toml"}
+
+// Environment variables recognized by Parse.
+const (
+	EnvConfFile    = "M3_CONF"
+	EnvSpecFile    = "M3_SPEC"
+	EnvRemote      = "M3_REMOTE"
+	EnvTargetDir   = "M3_DIR"
+	EnvModDir      = "M3_MODDIR"
+	EnvConcurrency = "M3_CONCURRENCY"
+	EnvCurseApiKey = "M3_CURSE_API_KEY"
+	EnvGitHubToken = "M3_GITHUB_TOKEN"
+	EnvMinecraft   = "M3_MINECRAFT_DIR"
+)
+
+// Config values represent the complete runtime configuration for m3.
+type Config struct {
+	// Local is the path to a local spec file.
+	Local string
+	// Remote is the URL of a remote spec file. If set, it takes
+	// precedence over Local.
+	Remote string
+	// Env describes the local installation environment.
+	Env Env
+	// Install describes which loader installation steps to perform.
+	Install Install
+	// Verbose enables verbose output.
+	Verbose bool
+	// VeryVerbose enables very verbose output. Implies Verbose.
+	VeryVerbose bool
+	// DryRun prints the planned changes instead of applying them.
+	DryRun bool
+	// Json selects JSON output where supported.
+	Json bool
+	// Locked installs strictly from the lockfile.
+	Locked bool
+	// Resolve adds missing mod dependencies to the lockfile.
+	Resolve bool
+	// From is the mod directory a new spec is created from.
+	From string
+	// Enable and Disable list the optional features to select or deselect.
+	Enable  []string
+	Disable []string
+	// CurseApiKey is the key used to access the CurseForge API.
+	CurseApiKey string
+	// GitHubToken is the token used to access the GitHub API.
+	GitHubToken string
+	// Args are the command line arguments remaining after the flags.
+	Args []string
+}
+
+// Env values describe the local installation environment.
+type Env struct {
+	// TargetDir is the directory the modpack is installed into.
+	TargetDir string
+	// ModDir is the mod directory, relative to TargetDir.
+	ModDir string
+	// Concurrency is the maximum number of simultaneous downloads.
+	Concurrency int
+	// MinecraftDir is the launcher's .minecraft directory, used for
+	// client installs.
+	MinecraftDir string
+}
+
+// Install values describe which loader installation steps to perform.
+type Install struct {
+	Client bool
+	Server bool
+	// Profile is the name of the launcher profile for client installs.
+	// Defaults to the name of the target directory.
+	Profile string
+}
+
+// Side returns the side being installed: "client" or "server" if only
+// one of them is selected, or an empty string for both.
+func (this *Install) Side() string {
+	switch {
+	case this.Client && !this.Server:
+		return "client"
+	case this.Server && !this.Client:
+		return "server"
+	}
+	return ""
+}
+
+// File values act as JSON import containers for the m3.conf file.
+type File struct {
+	Local       string
+	Remote      string
+	CurseApiKey string
+	GitHubToken string
+}
+
+// New returns a new Config populated with the built-in defaults.
+func New() *Config {
+	return &Config{
+		Local: DefaultSpecFile,
+		Env: Env{DefaultTargetDir, DefaultModDir, DefaultConcurrency,
+			loader.DefaultMinecraftDir()},
+	}
+}
+
+// Parse builds the Config from the program's command line arguments,
+// the environment and the m3.conf file. Errors are printed and cause
+// the program to exit, consistent with the standard flag package.
+func Parse() *Config {
+	conf, err := ParseArgs(os.Args[0], os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	return conf
+}
+
+// ParseArgs builds the Config from the given command line arguments, the
+// environment and the m3.conf file, applying the documented precedence.
+func ParseArgs(name string, args []string) (*Config, error) {
+	conf := New()
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags := conf.bind(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	// Apply the conf file, then the environment, then explicit flags
+	if err := conf.LoadFile(os.Getenv(EnvConfFile)); err != nil {
+		return nil, err
+	}
+	if err := conf.LoadEnv(); err != nil {
+		return nil, err
+	}
+	fs.Visit(func(f *flag.Flag) { flags[f.Name](f.Value.String()) })
+	conf.Args = fs.Args()
+	conf.findSpec()
+	return conf, conf.Validate()
+}
+
+// bind registers the m3 command line flags on the given FlagSet. Flag
+// values are parsed into temporaries and only applied to the Config if
+// the flag was set explicitly, so that lower precedence sources are not
+// overridden by flag defaults. The returned map holds the setter for
+// each flag name.
+func (this *Config) bind(fs *flag.FlagSet) map[string]func(string) {
+	fs.String("f", this.Local, "Specification to import (file or URL)")
+	fs.String("dir", this.Env.TargetDir, "Set the working directory")
+	fs.String("moddir", this.Env.ModDir, "Set the mod directory")
+	fs.Int("n", this.Env.Concurrency, "Max concurrent downloads")
+	fs.Bool("server", false, "Run installer in server mode")
+	fs.Bool("client", false, "Run installer in client mode")
+	fs.String("mcdir", this.Env.MinecraftDir, "Set the launcher directory for client mode")
+	fs.String("profile", "", "Set the launcher profile name for client mode")
+	fs.Bool("v", false, "Use verbose output")
+	fs.Bool("vv", false, "Use very verbose output")
+	fs.Bool("dry-run", false, "Print planned changes without applying them")
+	fs.Bool("json", false, "Use JSON output where supported")
+	fs.Bool("locked", false, "Install strictly from the lockfile")
+	fs.Bool("resolve", false, "Add missing mod dependencies to the lockfile")
+	fs.String("from", "", "Create the spec from the jars in this directory")
+	fs.String("enable", "", "Select optional features (comma-separated)")
+	fs.String("disable", "", "Deselect optional features (comma-separated)")
+	return map[string]func(string){
+		"f":       this.setSpec,
+		"dir":     func(v string) { this.Env.TargetDir = v },
+		"moddir":  func(v string) { this.Env.ModDir = v },
+		"n":       func(v string) { this.Env.Concurrency, _ = strconv.Atoi(v) },
+		"server":  func(v string) { this.Install.Server = v == "true" },
+		"client":  func(v string) { this.Install.Client = v == "true" },
+		"mcdir":   func(v string) { this.Env.MinecraftDir = v },
+		"profile": func(v string) { this.Install.Profile = v },
+		"v":       func(v string) { this.Verbose = this.Verbose || v == "true" },
+		"dry-run": func(v string) { this.DryRun = v == "true" },
+		"json":    func(v string) { this.Json = v == "true" },
+		"locked":  func(v string) { this.Locked = v == "true" },
+		"resolve": func(v string) { this.Resolve = v == "true" },
+		"from":    func(v string) { this.From = v },
+		"enable":  func(v string) { this.Enable = splitList(v) },
+		"disable": func(v string) { this.Disable = splitList(v) },
+		"vv": func(v string) {
+			this.VeryVerbose = v == "true"
+			this.Verbose = this.Verbose || this.VeryVerbose
+		},
+	}
+}
+
+// LoadFile applies the settings in the given m3.conf file. If 'file' is
+// empty the default location is used, and a missing default file is not
+// considered an error. Keys prefixed with an underscore are ignored,
+// which allows entries to be disabled without removing them.
+func (this *Config) LoadFile(file string) error {
+	explicit := file != ""
+	if !explicit {
+		file = DefaultConfFile
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var raw File
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("error: invalid config file %s: %v", file, err)
+	}
+	if raw.Local != "" {
+		this.Local = raw.Local
+	}
+	if raw.Remote != "" {
+		this.Remote = normalizeUrl(raw.Remote)
+	}
+	if raw.CurseApiKey != "" {
+		this.CurseApiKey = raw.CurseApiKey
+	}
+	if raw.GitHubToken != "" {
+		this.GitHubToken = raw.GitHubToken
+	}
+	return nil
+}
+
+// LoadEnv applies any settings defined in the environment.
+func (this *Config) LoadEnv() error {
+	if v := os.Getenv(EnvSpecFile); v != "" {
+		this.setSpec(v)
+	}
+	if v := os.Getenv(EnvRemote); v != "" {
+		this.Remote = normalizeUrl(v)
+	}
+	if v := os.Getenv(EnvTargetDir); v != "" {
+		this.Env.TargetDir = v
+	}
+	if v := os.Getenv(EnvModDir); v != "" {
+		this.Env.ModDir = v
+	}
+	if v := os.Getenv(EnvMinecraft); v != "" {
+		this.Env.MinecraftDir = v
+	}
+	if v := os.Getenv(EnvCurseApiKey); v != "" {
+		this.CurseApiKey = v
+	}
+	if v := os.Getenv(EnvGitHubToken); v != "" {
+		this.GitHubToken = v
+	}
+	if v := os.Getenv(EnvConcurrency); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("error: invalid %s value %q", EnvConcurrency, v)
+		}
+		this.Env.Concurrency = n
+	}
+	return nil
+}
+
+// Validate checks the Config for invalid settings.
+func (this *Config) Validate() error {
+	if this.Env.Concurrency < 1 {
+		return fmt.Errorf("error: concurrency must be at least 1")
+	}
+	if this.Local == "" && this.Remote == "" {
+		return fmt.Errorf("error: no specification given")
+	}
+	return nil
+}
+
+// GetSpec imports the Spec from the configured remote URL, or from the
+// local spec file if no remote URL is set. In locked mode the contents
+// of the lockfile are applied to the Spec.
+func (this *Config) GetSpec() (*spec.Spec, error) {
+	mod.CurseApiKey = this.CurseApiKey
+	git.Token = this.GitHubToken
+	if !this.Locked {
+		if this.Remote != "" {
+			return spec.FromRemote(this.Remote)
+		}
+		return spec.FromFile(this.Local)
+	}
+	lock, err := spec.ReadLock(this.LockFile())
+	if err != nil {
+		return nil, err
+	}
+	raw, err := this.GetRaw()
+	if err != nil {
+		return nil, err
+	}
+	base := this.Remote
+	if base == "" {
+		base = filepath.Dir(this.Local)
+	}
+	return spec.NewLocked(raw, base, lock)
+}
+
+// GetRaw returns the fully resolved Raw spec from the configured remote
+// URL, or from the local spec file if no remote URL is set, without
+// initializing its mods or loader.
+func (this *Config) GetRaw() (*spec.Raw, error) {
+	if this.Remote != "" {
+		return spec.ReadRemote(this.Remote)
+	}
+	return spec.ReadFile(this.Local)
+}
+
+// LockFile returns the path of the lockfile for the configured spec. The
+// lockfile is kept next to a local spec, or in the target directory for
+// remote specs.
+func (this *Config) LockFile() string {
+	if this.Remote != "" {
+		return filepath.Join(this.Env.TargetDir, spec.DefaultLockFile)
+	}
+	return filepath.Join(filepath.Dir(this.Local), spec.DefaultLockFile)
+}
+
+// SelectionFile returns the path of the file recording the selected
+// optional features. Like the lockfile, it is kept next to a local spec,
+// or in the target directory for remote specs.
+func (this *Config) SelectionFile() string {
+	if this.Remote != "" {
+		return filepath.Join(this.Env.TargetDir, DefaultSelectionFile)
+	}
+	return filepath.Join(filepath.Dir(this.Local), DefaultSelectionFile)
+}
+
+// findSpec falls back to the first of the DefaultSpecFiles that exists if
+// the default local spec file is used but does not exist.
+func (this *Config) findSpec() {
+	if this.Remote != "" || this.Local != DefaultSpecFile {
+		return
+	}
+	if _, err := os.Stat(this.Local); !os.IsNotExist(err) {
+		return
+	}
+	for _, el := range DefaultSpecFiles {
+		if _, err := os.Stat(el); err == nil {
+			this.Local = el
+			return
+		}
+	}
+}
+
+// setSpec sets the spec source from a file path or URL. An explicitly
+// chosen source replaces any previously configured one.
+func (this *Config) setSpec(v string) {
+	if isUrl(v) {
+		this.Local, this.Remote = "", normalizeUrl(v)
+	} else {
+		this.Local, this.Remote = v, ""
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	list := []string{}
+	for _, el := range strings.Split(s, ",") {
+		if el = strings.TrimSpace(el); el != "" {
+			list = append(list, el)
+		}
+	}
+	return list
+}
+
+// isUrl reports whether the string refers to a remote location.
+func isUrl(s string) bool {
+	return strings.HasPrefix(s, "http://") ||
+		strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "//")
+}
+
+// normalizeUrl adds the https scheme to scheme-relative URLs.
+func normalizeUrl(s string) string {
+	if strings.HasPrefix(s, "//") {
+		return "https:" + s
+	}
+	return s
+}
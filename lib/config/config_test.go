@@ -0,0 +1,110 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// setup points the environment at an m3.conf file with the given
+// contents, and clears the other environment variables read by Parse.
+func setup(t *testing.T, conf string) {
+	for _, el := range []string{EnvSpecFile, EnvRemote, EnvTargetDir, EnvModDir,
+		EnvConcurrency, EnvCurseApiKey, EnvGitHubToken, EnvMinecraft} {
+		t.Setenv(el, "")
+	}
+	file := filepath.Join(t.TempDir(), DefaultConfFile)
+	if err := ioutil.WriteFile(file, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvConfFile, file)
+}
+
+func TestPrecedence(t *testing.T) {
+	for _, el := range []struct {
+		Name string
+		Conf string
+		Env  map[string]string
+		Args []string
+		// Local, Remote and CurseApiKey of the resulting Config
+		Want [3]string
+	}{
+		{"default", `{}`, nil, nil, [3]string{DefaultSpecFile, "", ""}},
+		{"file", `{"Local": "file.json", "CurseApiKey": "file"}`, nil, nil,
+			[3]string{"file.json", "", "file"}},
+		{"env over file", `{"Local": "file.json", "CurseApiKey": "file"}`,
+			map[string]string{EnvSpecFile: "env.json", EnvCurseApiKey: "env"}, nil,
+			[3]string{"env.json", "", "env"}},
+		{"flag over env", `{"Local": "file.json"}`, map[string]string{EnvSpecFile: "env.json"},
+			[]string{"-f", "flag.json"}, [3]string{"flag.json", "", ""}},
+		{"remote flag over local env", `{}`, map[string]string{EnvSpecFile: "env.json"},
+			[]string{"-f", "//example.com/modpack.json"}, [3]string{"", "https://example.com/modpack.json", ""}},
+		{"local flag over remote file", `{"Remote": "https://example.com/modpack.json"}`, nil,
+			[]string{"-f", "flag.json"}, [3]string{"flag.json", "", ""}},
+	} {
+		t.Run(el.Name, func(t *testing.T) {
+			setup(t, el.Conf)
+			for k, v := range el.Env {
+				t.Setenv(k, v)
+			}
+			conf, err := ParseArgs("m3", el.Args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := [3]string{conf.Local, conf.Remote, conf.CurseApiKey}; got != el.Want {
+				t.Errorf("got %q, want %q", got, el.Want)
+			}
+		})
+	}
+}
+
+func TestPrecedenceEnv(t *testing.T) {
+	setup(t, `{}`)
+	t.Setenv(EnvTargetDir, "env")
+	t.Setenv(EnvConcurrency, "5")
+
+	// Unset flags do not override the environment with their defaults
+	conf, err := ParseArgs("m3", []string{"-moddir", "flag", "install"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Env.TargetDir != "env" || conf.Env.ModDir != "flag" || conf.Env.Concurrency != 5 {
+		t.Errorf("got env %+v", conf.Env)
+	}
+	if len(conf.Args) != 1 || conf.Args[0] != "install" {
+		t.Errorf("got args %v", conf.Args)
+	}
+	if conf, err = ParseArgs("m3", []string{"-dir", "flag", "-n", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Env.TargetDir != "flag" || conf.Env.Concurrency != 2 {
+		t.Errorf("got env %+v", conf.Env)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, el := range []struct {
+		Name string
+		Conf string
+		Env  string
+		Args []string
+	}{
+		{"invalid file", `{"Local": `, "", nil},
+		{"invalid env", `{}`, "many", nil},
+		{"invalid flag", `{}`, "", []string{"-n", "0"}},
+		{"no spec", `{}`, "", []string{"-f", ""}},
+	} {
+		t.Run(el.Name, func(t *testing.T) {
+			setup(t, el.Conf)
+			t.Setenv(EnvConcurrency, el.Env)
+			if _, err := ParseArgs("m3", el.Args); err == nil {
+				t.Error("invalid config accepted")
+			}
+		})
+	}
+	setup(t, `{}`)
+	t.Setenv(EnvConfFile, filepath.Join(t.TempDir(), "missing.conf"))
+	if _, err := ParseArgs("m3", nil); err == nil {
+		t.Error("missing explicit config file accepted")
+	}
+}
@@ -0,0 +1,40 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Release values represent releases returned from the GitHub Releases API.
+type Release struct {
+	Tag_name string
+	Name     string
+	Assets   []Asset
+}
+
+// Asset values represent files attached to a GitHub release.
+type Asset struct {
+	Name                 string
+	Size                 int64
+	Url                  string
+	Browser_download_url string
+	// Digest is the published checksum of the asset, in the form
+	// "<algorithm>:<checksum>". Not present for older assets.
+	Digest string
+}
+
+// Release returns the release of the Repository with the given tag. The
+// tag "latest" refers to the most recent non-prerelease release.
+func (this *Repository) Release(tag string) (*Release, error) {
+	endpoint := "/releases/latest"
+	if tag != "" && tag != "latest" {
+		endpoint = "/releases/tags/" + url.PathEscape(tag)
+	}
+	var release Release
+	err := this.get(endpoint, &release)
+	if err != nil {
+		return nil, fmt.Errorf("error: release %s not found for repository %s/%s: %v",
+			tag, this.Owner, this.Name, err)
+	}
+	return &release, nil
+}
@@ -1,18 +1,33 @@
 package git
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"github.com/faceless-saint/m3/lib/net"
 	"net/http"
 	"path"
 	"strings"
 )
 
+// Token is the GitHub access token sent with all API requests, if set.
+// It is required to access private repositories.
+var Token = ""
+
+// Header returns the headers for GitHub API requests.
+func Header() http.Header {
+	header := http.Header{}
+	if Token != "" {
+		header.Set("Authorization", "Bearer "+Token)
+	}
+	return header
+}
+
 // Repository values represent a public repository on GitHub.
 type Repository struct {
 	Owner string
 	Name  string
+	// Ref is the branch, tag or commit to read. The default branch of
+	// the repository is used if Ref is empty.
+	Ref string
 }
 
 // NewRepository takes a string in the form "<owner>/<repo>" and
@@ -22,7 +37,7 @@ func NewRepository(repository string) (*Repository, error) {
 	if len(split) != 2 {
 		return nil, fmt.Errorf("error: invalid repository name")
 	}
-	return &Repository{split[0], split[1]}, nil
+	return &Repository{split[0], split[1], ""}, nil
 }
 
 // Url returns the content URL for the Repository.
@@ -35,19 +50,29 @@ func (this *Repository) ContentPath() string {
 // An empty path maps to the root of the repository path structure.
 func (this *Repository) Explore(p string) (ContentList, error) {
 	content := ContentList{}
-	resp, err := http.Get("https://" + path.Join(this.ContentPath(), p))
-	if err != nil {
-		return nil, err
+	url := "https://" + path.Join(this.ContentPath(), p)
+	if this.Ref != "" {
+		url += "?ref=" + this.Ref
 	}
-	defer resp.Body.Close()
-	results, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	err = json.Unmarshal(results, &content)
+	err := net.GetJSON(url, Header(), &content)
 	return content, err
 }
 
+// Commit returns the SHA of the commit the Repository's Ref points to.
+func (this *Repository) Commit() (string, error) {
+	ref := this.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	var commit struct{ Sha string }
+	err := this.get("/commits/"+ref, &commit)
+	if err == nil && commit.Sha == "" {
+		err = fmt.Errorf("error: unknown ref %s for repository %s/%s",
+			ref, this.Owner, this.Name)
+	}
+	return commit.Sha, err
+}
+
 // Aggregate returns a flat list of Content values from the path and all
 // subdirectories under it, recursively. Only file elements are returned.
 func (this *Repository) Aggregate(p string) (ContentList, error) {
@@ -71,3 +96,10 @@ func (this *Repository) Aggregate(p string) (ContentList, error) {
 	}
 	return full, nil
 }
+
+// get decodes the JSON response of a GitHub API request for the given
+// endpoint of the Repository into 'v'.
+func (this *Repository) get(endpoint string, v interface{}) error {
+	return net.GetJSON(fmt.Sprintf("https://api.github.com/repos/%s/%s%s",
+		this.Owner, this.Name, endpoint), Header(), v)
+}
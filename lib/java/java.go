@@ -0,0 +1,200 @@
+/* Java is a library for discovering the Java runtimes installed on the
+ * system and selecting one that satisfies the Java version required by a
+ * Minecraft version.
+ */
+package java
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Locations are the glob patterns of common Java install locations, by
+// operating system.
+var Locations = map[string][]string{
+	"linux": {
+		"/usr/lib/jvm/*/bin/java",
+		"/usr/java/*/bin/java",
+		"/opt/java/*/bin/java",
+		"/opt/jdk*/bin/java",
+	},
+	"darwin": {
+		"/Library/Java/JavaVirtualMachines/*/Contents/Home/bin/java",
+		"/opt/homebrew/opt/openjdk*/bin/java",
+		"/usr/local/opt/openjdk*/bin/java",
+	},
+	"windows": {
+		`C:\Program Files\Java\*\bin\java.exe`,
+		`C:\Program Files\Eclipse Adoptium\*\bin\java.exe`,
+		`C:\Program Files\Microsoft\jdk-*\bin\java.exe`,
+		`C:\Program Files\Zulu\*\bin\java.exe`,
+	},
+}
+
+// Runtime values represent Java runtimes.
+type Runtime struct {
+	// Path is the Java executable.
+	Path string
+	// Version is the full version reported by the runtime.
+	Version string
+	// Major is the major Java version, such as 8 or 17.
+	Major int
+}
+
+func (this *Runtime) String() string {
+	return fmt.Sprintf("java %s (%s)", this.Version, this.Path)
+}
+
+// Satisfies returns true iff the runtime can be used where the given major
+// Java version is required. Java 8 is required exactly, as older Minecraft
+// versions fail on newer runtimes; from Java 16 on, newer runtimes are
+// accepted.
+func (this *Runtime) Satisfies(major int) bool {
+	if major < 16 {
+		return this.Major == major
+	}
+	return this.Major >= major
+}
+
+// Required returns the major Java version required by the given Minecraft
+// version, or zero if it is not a release version such as "1.20.1".
+func Required(minecraft string) int {
+	split := strings.Split(minecraft, ".")
+	if len(split) < 2 || len(split) > 3 || split[0] != "1" {
+		return 0
+	}
+	minor, err := strconv.Atoi(split[1])
+	if err != nil {
+		return 0
+	}
+	patch := 0
+	if len(split) > 2 {
+		if patch, err = strconv.Atoi(split[2]); err != nil {
+			return 0
+		}
+	}
+	switch {
+	case minor > 20 || minor == 20 && patch >= 5:
+		return 21
+	case minor >= 18:
+		return 17
+	case minor == 17:
+		return 16
+	default:
+		return 8
+	}
+}
+
+// Find returns the Java runtimes found in JAVA_HOME, the PATH and the
+// common install Locations, in that order.
+func Find() []*Runtime {
+	found, seen := []*Runtime{}, map[string]*struct{}{}
+	for _, el := range candidates() {
+		path, err := filepath.EvalSymlinks(el)
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = new(struct{})
+		if rt, err := Detect(el); err == nil {
+			found = append(found, rt)
+		}
+	}
+	return found
+}
+
+// Select returns the first of the runtimes satisfying the given major
+// Java version, preferring an exact match.
+func Select(runtimes []*Runtime, major int) (*Runtime, error) {
+	var match *Runtime
+	for _, el := range runtimes {
+		if el.Major == major {
+			return el, nil
+		} else if match == nil && el.Satisfies(major) {
+			match = el
+		}
+	}
+	if match != nil {
+		return match, nil
+	}
+	versions := []string{}
+	for _, el := range runtimes {
+		versions = append(versions, strconv.Itoa(el.Major))
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("error: java %d is required but no java runtime was found - "+
+			"install java %d or set JAVA_HOME", major, major)
+	}
+	return nil, fmt.Errorf("error: java %d is required but only java %s was found - "+
+		"install java %d or set JAVA_HOME", major, strings.Join(versions, ", "), major)
+}
+
+// Detect returns the Runtime of the given Java executable.
+func Detect(path string) (*Runtime, error) {
+	out, err := exec.Command(path, "-version").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error: failed to run %s: %v", path, err)
+	}
+	version, major, err := ParseVersion(string(out))
+	if err != nil {
+		return nil, err
+	}
+	return &Runtime{path, version, major}, nil
+}
+
+var versionPattern = regexp.MustCompile(`version "([^"]+)"`)
+
+// ParseVersion returns the full and major Java version from the output of
+// 'java -version'. Both the legacy ("1.8.0_292") and the current ("17.0.2")
+// version schemes are supported.
+func ParseVersion(output string) (string, int, error) {
+	match := versionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return "", 0, fmt.Errorf("error: unrecognized java version output %q", output)
+	}
+	version := match[1]
+	split := strings.FieldsFunc(version, func(r rune) bool {
+		return r == '.' || r == '_' || r == '-' || r == '+'
+	})
+	if len(split) > 1 && split[0] == "1" {
+		split = split[1:]
+	}
+	major, err := 0, fmt.Errorf("no major version")
+	if len(split) > 0 {
+		major, err = strconv.Atoi(split[0])
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("error: invalid java version %q", version)
+	}
+	return version, major, nil
+}
+
+// candidates returns the paths of possible Java executables.
+func candidates() []string {
+	exe := "java"
+	if runtime.GOOS == "windows" {
+		exe = "java.exe"
+	}
+	list := []string{}
+	if home := os.Getenv("JAVA_HOME"); home != "" {
+		list = append(list, filepath.Join(home, "bin", exe))
+	}
+	for _, el := range filepath.SplitList(os.Getenv("PATH")) {
+		if el != "" {
+			list = append(list, filepath.Join(el, exe))
+		}
+	}
+	for _, pattern := range Locations[runtime.GOOS] {
+		matches, _ := filepath.Glob(pattern)
+		list = append(list, matches...)
+	}
+	return list
+}
@@ -0,0 +1,14 @@
+package java
+
+import "testing"
+
+func TestRequired(t *testing.T) {
+	for minecraft, want := range map[string]int{
+		"1.12.2": 8, "1.16.5": 8, "1.17.1": 16, "1.18": 17, "1.20.4": 17, "1.20.5": 21, "1.21": 21,
+		"": 0, "24w14a": 0, "1.20.x": 0, "2.0": 0, "1": 0,
+	} {
+		if got := Required(minecraft); got != want {
+			t.Errorf("Required(%q) = %d, want %d", minecraft, got, want)
+		}
+	}
+}
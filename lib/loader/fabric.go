@@ -0,0 +1,220 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/plan"
+	"hash"
+	"path/filepath"
+	"strings"
+)
+
+// FabricMeta is the base URL of the Fabric meta API.
+var FabricMeta = "https://meta.fabricmc.net"
+
+// Fabric values represent Fabric server launchers. The loader version may
+// be a pattern such as "0.15.x", which resolves to the newest matching
+// loader version, preferring stable releases. An empty version resolves
+// to the latest stable loader.
+type Fabric struct {
+	// Game is the Minecraft version.
+	Game string
+	// Version is the loader version as given in the spec.
+	Version string
+	// Loader is the resolved loader version.
+	Loader string
+	// InstallerVersion is the version of the Fabric installer providing
+	// the server launcher.
+	InstallerVersion string
+	checksum         string
+	hash             hash.Hash
+}
+
+// fabricVersion values act as JSON import containers for the versions
+// listed by the Fabric meta API.
+type fabricVersion struct {
+	Version string
+	Stable  bool
+}
+
+// fabricProfile values act as JSON import containers for Fabric launcher
+// profiles.
+type fabricProfile struct {
+	Id        string
+	Libraries []RawLibrary
+}
+
+// NewFabric returns a new Fabric loader for the given Minecraft and
+// loader versions, resolved through the Fabric meta API.
+func NewFabric(minecraft, version, checksum string, h hash.Hash) (*Fabric, error) {
+	if minecraft == "" {
+		return nil, fmt.Errorf("error: the fabric loader requires a 'minecraft' version")
+	}
+	this := Fabric{Game: minecraft, Version: version, checksum: checksum, hash: h}
+	return &this, this.Init()
+}
+
+// Init resolves the loader and installer versions.
+func (this *Fabric) Init() error {
+	var loaders []struct{ Loader fabricVersion }
+	err := net.GetJSON(FabricMeta+"/v2/versions/loader/"+this.Game, nil, &loaders)
+	if err != nil {
+		return err
+	}
+	versions := []fabricVersion{}
+	for _, el := range loaders {
+		versions = append(versions, el.Loader)
+	}
+	if this.Loader = matchVersion(versions, this.Version); this.Loader == "" {
+		return fmt.Errorf("error: no fabric loader matching %q found for minecraft %s",
+			this.Version, this.Game)
+	}
+	var installers []fabricVersion
+	err = net.GetJSON(FabricMeta+"/v2/versions/installer", nil, &installers)
+	if err != nil {
+		return err
+	}
+	if this.InstallerVersion = matchVersion(installers, ""); this.InstallerVersion == "" {
+		return fmt.Errorf("error: no fabric installer found")
+	}
+	return nil
+}
+
+func (this *Fabric) Filename() string {
+	return fmt.Sprintf("fabric-server-mc.%s-loader.%s-launcher.%s.jar",
+		this.Game, this.Loader, this.InstallerVersion)
+}
+func (this *Fabric) Url() string {
+	return fmt.Sprintf("%s/v2/versions/loader/%s/%s/%s/server/jar",
+		FabricMeta, this.Game, this.Loader, this.InstallerVersion)
+}
+func (this *Fabric) Checksum() string  { return this.checksum }
+func (this *Fabric) Hash() hash.Hash   { return this.hash }
+func (this *Fabric) Name() string      { return "fabric" }
+func (this *Fabric) Minecraft() string { return this.Game }
+func (this *Fabric) String() string {
+	return fmt.Sprintf("fabric %s (minecraft %s)", this.Loader, this.Game)
+}
+
+// Provides returns the versions of the "minecraft" and "fabricloader" mod
+// IDs.
+func (this *Fabric) Provides() map[string]string {
+	return map[string]string{"minecraft": this.Game, "fabricloader": this.Loader}
+}
+
+func (this *Fabric) SetChecksum(checksum string, h hash.Hash) {
+	this.checksum, this.hash = checksum, h
+}
+
+// Install installs the libraries required by the server launcher, and
+// adds a launcher profile for the client if requested.
+func (this *Fabric) Install(opts *Options) error {
+	if opts.Server {
+		var profile fabricProfile
+		if err := net.GetJSON(this.profileUrl("server"), nil, &profile); err != nil {
+			return err
+		}
+		libs := []*Library{}
+		for i := range profile.Libraries {
+			lib, err := NewLibrary(&profile.Libraries[i])
+			if err != nil {
+				return err
+			}
+			libs = append(libs, lib)
+		}
+		if err := getLibraries(LibraryDir, libs, opts); err != nil {
+			return err
+		}
+	}
+	if opts.Client {
+		// The launcher downloads the libraries of the client profile
+		data, err := net.Get(this.profileUrl("profile"), nil)
+		if err != nil {
+			return err
+		}
+		var profile fabricProfile
+		if err := json.Unmarshal(data, &profile); err != nil {
+			return err
+		}
+		return addVersion(opts, profile.Id, data)
+	}
+	return nil
+}
+
+// Plan computes the changes needed to prepare the working directory for
+// the Fabric server launcher. Stale launchers and the libraries of stale
+// loader and Minecraft versions are deleted.
+func (this *Fabric) Plan() (plan.Plan, error) {
+	p := plan.Plan{}
+	valid, err := clean(&p, this)
+	if err != nil {
+		return nil, err
+	}
+	for _, el := range []struct{ Dir, Keep string }{
+		{"net/fabricmc/fabric-loader", this.Loader},
+		{"net/fabricmc/intermediary", this.Game},
+	} {
+		dirs, _ := filepath.Glob(filepath.Join(LibraryDir, filepath.FromSlash(el.Dir), "*"))
+		for _, dir := range dirs {
+			if filepath.Base(dir) != el.Keep {
+				p.Add(plan.Delete, dir)
+			}
+		}
+	}
+	if !valid {
+		download(&p, this)
+	}
+	return p, nil
+}
+
+// profileUrl returns the URL of the Fabric launcher profile of the given
+// type, either "profile" for clients or "server".
+func (this *Fabric) profileUrl(kind string) string {
+	return fmt.Sprintf("%s/v2/versions/loader/%s/%s/%s/json",
+		FabricMeta, this.Game, this.Loader, kind)
+}
+
+// matchVersion returns the first version in the list matching the given
+// pattern, preferring stable versions. Pattern segments of "x" or "*"
+// match any value, and a pattern matches all versions it is a prefix of.
+// An empty pattern matches any stable version.
+func matchVersion(versions []fabricVersion, pattern string) string {
+	for _, el := range versions {
+		if el.Version == pattern {
+			return el.Version
+		}
+	}
+	match := ""
+	for _, el := range versions {
+		if pattern == "" && !el.Stable {
+			continue
+		}
+		if !matchPattern(pattern, el.Version) {
+			continue
+		}
+		if el.Stable {
+			return el.Version
+		} else if match == "" {
+			match = el.Version
+		}
+	}
+	return match
+}
+
+// matchPattern returns true iff the version matches the pattern.
+func matchPattern(pattern, version string) bool {
+	if pattern == "" {
+		return true
+	}
+	want, have := strings.Split(pattern, "."), strings.Split(version, ".")
+	if len(want) > len(have) {
+		return false
+	}
+	for i, el := range want {
+		if el != "x" && el != "*" && el != have[i] {
+			return false
+		}
+	}
+	return true
+}
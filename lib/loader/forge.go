@@ -0,0 +1,439 @@
+package loader
+
+import (
+	"fmt"
+	"github.com/cavaliercoder/grab"
+	"github.com/faceless-saint/m3/lib/maven"
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/plan"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ForgeMaven is the URL of the Maven repository hosting Forge.
+const ForgeMaven = "https://maven.minecraftforge.net"
+
+// NeoForgeMaven is the URL of the Maven repository hosting NeoForge.
+const NeoForgeMaven = "https://maven.neoforged.net/releases"
+
+// Distribution values identify a Forge distribution by its Maven artifact.
+type Distribution struct {
+	Name       string
+	Group      string
+	Artifact   string
+	Repository string
+}
+
+// Known Forge distributions. NeoForge for Minecraft 1.20.1 was published
+// under the original Forge artifact name.
+var (
+	MinecraftForge = Distribution{"forge", "net.minecraftforge", "forge", ForgeMaven}
+	NeoForge       = Distribution{"neoforge", "net.neoforged", "neoforge", NeoForgeMaven}
+	NeoForgeLegacy = Distribution{"neoforge", "net.neoforged", "forge", NeoForgeMaven}
+)
+
+var distributions = []*Distribution{&MinecraftForge, &NeoForge, &NeoForgeLegacy}
+
+// Forge server install layouts, by Minecraft version.
+const (
+	// layoutUniversal installs forge-<version>-universal.jar (up to 1.12).
+	layoutUniversal = iota
+	// layoutJar installs forge-<version>.jar (1.13 to 1.16).
+	layoutJar
+	// layoutArgs installs the server jar and its launch arguments into
+	// the libraries tree, along with run scripts (1.17 and later).
+	layoutArgs
+)
+
+// Forge values represent downloadable Forge installer files.
+type Forge struct {
+	Version        string
+	checksum       string
+	hash           hash.Hash
+	ServerChecksum string
+	// Dist is the Forge distribution. Defaults to MinecraftForge.
+	Dist *Distribution
+}
+
+// NewNeoForge returns a new NeoForge loader of the given version.
+func NewNeoForge(version, checksum string, h hash.Hash) *Forge {
+	dist := &NeoForge
+	if strings.HasPrefix(version, "1.") {
+		dist = &NeoForgeLegacy
+	}
+	return &Forge{Version: version, checksum: checksum, hash: h, Dist: dist}
+}
+
+// Coordinate returns the Maven coordinate of the Forge installer.
+func (this *Forge) Coordinate() *maven.Coordinate {
+	return &maven.Coordinate{Group: this.dist().Group, Artifact: this.dist().Artifact,
+		Version: this.Version, Classifier: "installer", Extension: "jar"}
+}
+
+func (this *Forge) Filename() string { return this.Coordinate().Filename() }
+func (this *Forge) Url() string      { return this.Coordinate().Url(this.dist().Repository) }
+func (this *Forge) Checksum() string { return this.checksum }
+func (this *Forge) Hash() hash.Hash  { return this.hash }
+func (this *Forge) Name() string     { return this.dist().Name }
+func (this *Forge) String() string   { return this.Name() + " " + this.Version }
+
+// Minecraft returns the Minecraft version targeted by the Forge version.
+// Forge versions are prefixed with the Minecraft version, while NeoForge
+// versions encode it in their first two components ("20.4.x" targets
+// Minecraft 1.20.4).
+func (this *Forge) Minecraft() string {
+	if this.dist() != &NeoForge {
+		return strings.SplitN(this.Version, "-", 2)[0]
+	}
+	split := strings.SplitN(this.Version, ".", 3)
+	if len(split) < 2 {
+		return ""
+	} else if split[1] == "0" {
+		return "1." + split[0]
+	}
+	return "1." + split[0] + "." + split[1]
+}
+
+// Provides returns the versions of the "minecraft" mod ID and of the
+// loader's mod ID. Forge versions, and those of NeoForge for Minecraft
+// 1.20.1, are prefixed with the Minecraft version and provide the loader
+// version that follows it; the latter also provides it as "forge". Other
+// NeoForge versions, such as "20.4.80-beta", are provided whole.
+func (this *Forge) Provides() map[string]string {
+	version := this.Version
+	if this.dist() != &NeoForge {
+		version = strings.TrimPrefix(version, this.Minecraft()+"-")
+	}
+	mods := map[string]string{"minecraft": this.Minecraft(), this.Name(): version}
+	if this.dist() == &NeoForgeLegacy {
+		mods["forge"] = mods["neoforge"]
+	}
+	return mods
+}
+
+func (this *Forge) SetChecksum(checksum string, h hash.Hash) {
+	this.checksum, this.hash = checksum, h
+}
+
+// Fetch downloads the forge installer for the Spec
+func (this *Forge) Fetch(verbose bool) (*grab.Response, error) {
+	// Prepare the working directory
+	err := this.clean()
+	if err != nil {
+		return nil, err
+	}
+	// Download the Forge installer
+	return net.GetFile(this, "")
+}
+
+// Install installs the Forge server files into the working directory, then
+// verifies them. For clients, Forge is installed into the launcher along
+// with a profile for the modpack.
+func (this *Forge) Install(opts *Options) error {
+	if opts.Server {
+		if err := this.installServer(opts); err != nil {
+			return err
+		}
+		if err := this.Verify(); err != nil {
+			return err
+		}
+	}
+	if opts.Client {
+		return this.installClient(opts)
+	}
+	return nil
+}
+
+// installServer installs the Forge server into the working directory
+// without running the installer itself: the libraries and the vanilla
+// server jar are downloaded and verified, and the server jar and launch
+// files are extracted from the installer. Only the installer processors
+// with missing outputs are run, which requires Java.
+func (this *Forge) installServer(opts *Options) error {
+	inst, err := openInstaller(this.Filename())
+	if err != nil {
+		return err
+	}
+	defer inst.Close()
+	root, err := filepath.Abs(".")
+	if err != nil {
+		return err
+	}
+	libDir := filepath.Join(root, LibraryDir)
+	if err := inst.installLibraries(libDir, "server", opts); err != nil {
+		return err
+	}
+	jar := inst.serverJar(root, libDir)
+	if err := installMinecraftServer(inst.minecraft(), jar, opts); err != nil {
+		return err
+	}
+	if err := inst.process("server", root, libDir, jar, opts); err != nil {
+		return err
+	}
+	if this.layout() != layoutArgs {
+		// Older layouts launch the Forge jar from the working directory
+		if err := opts.preserve(this.serverJar()); err != nil {
+			return err
+		}
+		if inst.legacy() {
+			return inst.extract(inst.Profile.Install.FilePath, this.serverJar())
+		}
+		data, err := ioutil.ReadFile(libraryPath(libDir, inst.Profile.Path))
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(this.serverJar(), data, 0644)
+	}
+	// Newer layouts launch through run scripts and argument files
+	for _, el := range []struct{ Name, File string }{
+		{"run.sh", "run.sh"},
+		{"run.bat", "run.bat"},
+		{"user_jvm_args.txt", "user_jvm_args.txt"},
+		{"unix_args.txt", filepath.Join(this.libraryDir(this.dist()), this.Version, "unix_args.txt")},
+		{"win_args.txt", filepath.Join(this.libraryDir(this.dist()), this.Version, "win_args.txt")},
+	} {
+		if !inst.has("data/" + el.Name) {
+			continue
+		}
+		if _, err := os.Stat(el.File); err == nil && el.Name == "user_jvm_args.txt" {
+			// Keep the server operator's JVM arguments
+			continue
+		}
+		if err := opts.preserve(el.File); err != nil {
+			return err
+		}
+		if err := inst.extract("data/"+el.Name, el.File); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat("run.sh"); err == nil {
+		return os.Chmod("run.sh", 0755)
+	}
+	return nil
+}
+
+// installClient installs Forge into the launcher in opts.MinecraftDir:
+// the vanilla client, the Forge libraries and version manifest, and a
+// launcher profile using opts.GameDir as its game directory. The client
+// files are patched by the installer processors, which require Java.
+func (this *Forge) installClient(opts *Options) error {
+	inst, err := openInstaller(this.Filename())
+	if err != nil {
+		return err
+	}
+	defer inst.Close()
+	dir := opts.MinecraftDir
+	jar, err := installMinecraft(dir, inst.minecraft(), opts)
+	if err != nil {
+		return err
+	}
+	libDir := filepath.Join(dir, LibraryDir)
+	if err := inst.installLibraries(libDir, "client", opts); err != nil {
+		return err
+	}
+	if err := inst.process("client", dir, libDir, jar, opts); err != nil {
+		return err
+	}
+	return addVersion(opts, inst.Version.Id, inst.versionData)
+}
+
+// UsesJava returns true iff installing Forge with the Options runs the
+// installer processors, which require Java. Versions up to 1.12 have no
+// processors. Otherwise they are assumed to run, unless the installer is
+// already in the working directory and shows their outputs are up to date.
+func (this *Forge) UsesJava(opts *Options) bool {
+	if this.layout() == layoutUniversal {
+		return false
+	}
+	inst, err := openInstaller(this.Filename())
+	if err != nil {
+		return true
+	}
+	defer inst.Close()
+	if opts.Server {
+		root, err := filepath.Abs(".")
+		if err != nil {
+			return true
+		}
+		libDir := filepath.Join(root, LibraryDir)
+		if run, err := inst.pending("server", root, libDir, inst.serverJar(root, libDir)); err != nil || run {
+			return true
+		}
+	}
+	if opts.Client {
+		dir, mc := opts.MinecraftDir, inst.minecraft()
+		jar := filepath.Join(dir, "versions", mc, mc+".jar")
+		if run, err := inst.pending("client", dir, filepath.Join(dir, LibraryDir), jar); err != nil || run {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks that the server files of the Forge version are installed.
+func (this *Forge) Verify() error {
+	for _, el := range this.serverFiles() {
+		if _, err := os.Stat(el); err != nil {
+			return fmt.Errorf("error: %s server files are incomplete - %s is missing", this, el)
+		}
+	}
+	if this.layout() == layoutUniversal {
+		match, err := net.CheckFile(this.serverJar(), this.ServerChecksum, this.Hash())
+		if err != nil {
+			return err
+		} else if !match {
+			return fmt.Errorf("error: checksum mismatch for %s", this.serverJar())
+		}
+	}
+	return nil
+}
+
+// Installed returns the versions of the Forge distribution with server
+// files in the working directory, detected from the libraries tree and
+// from the server jars of older layouts.
+func (this *Forge) Installed() []string {
+	found := map[string]*struct{}{}
+	dirs, _ := filepath.Glob(filepath.Join(this.libraryDir(this.dist()), "*"))
+	for _, el := range dirs {
+		if info, err := os.Stat(el); err == nil && info.IsDir() {
+			found[filepath.Base(el)] = new(struct{})
+		}
+	}
+	prefix := this.dist().Artifact + "-"
+	jars, _ := filepath.Glob(prefix + "*.jar")
+	for _, el := range jars {
+		version := strings.TrimSuffix(strings.TrimPrefix(el, prefix), ".jar")
+		if strings.HasSuffix(version, "-installer") {
+			continue
+		}
+		found[strings.TrimSuffix(version, "-universal")] = new(struct{})
+	}
+	versions := []string{}
+	for el := range found {
+		versions = append(versions, el)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Plan computes the changes that Fetch would make to the working
+// directory, without modifying it. Stale Forge installers are deleted,
+// along with the server jars and libraries of every other installed
+// version and distribution. Shared libraries are kept.
+func (this *Forge) Plan() (plan.Plan, error) {
+	p := plan.Plan{}
+	// Remove all invalid Forge installers and other loaders' files
+	valid, err := clean(&p, this)
+	if err != nil {
+		return nil, err
+	}
+	// Remove the server files of stale versions
+	for _, version := range this.Installed() {
+		if version == this.Version {
+			continue
+		}
+		prefix := this.dist().Artifact + "-" + version
+		for _, el := range []string{prefix + "-universal.jar", prefix + ".jar",
+			filepath.Join(this.libraryDir(this.dist()), version)} {
+			if _, err := os.Stat(el); err == nil {
+				p.Add(plan.Delete, el)
+			}
+		}
+	}
+	// Remove the server files of other distributions
+	for _, el := range distributions {
+		if *el == *this.dist() {
+			continue
+		}
+		if _, err := os.Stat(this.libraryDir(el)); err == nil {
+			p.Add(plan.Delete, this.libraryDir(el))
+		}
+	}
+	// Remove an invalid server jar of the current version
+	if this.layout() == layoutUniversal {
+		if _, err := os.Stat(this.serverJar()); err == nil {
+			match, err := net.CheckFile(this.serverJar(), this.ServerChecksum, this.Hash())
+			if err != nil {
+				return nil, err
+			}
+			if !match {
+				p.Add(plan.Delete, this.serverJar())
+			}
+		}
+	}
+	if !valid {
+		download(&p, this)
+	}
+	return p, nil
+}
+
+func (this *Forge) clean() error {
+	p, err := this.Plan()
+	if err != nil {
+		return err
+	}
+	return p.Apply()
+}
+
+// dist returns the Forge distribution, defaulting to MinecraftForge.
+func (this *Forge) dist() *Distribution {
+	if this.Dist == nil {
+		return &MinecraftForge
+	}
+	return this.Dist
+}
+
+// layout returns the server install layout of the Forge version.
+func (this *Forge) layout() int {
+	if this.dist() != &MinecraftForge {
+		return layoutArgs
+	}
+	split := strings.Split(this.Minecraft(), ".")
+	if len(split) < 2 {
+		return layoutArgs
+	}
+	minor, err := strconv.Atoi(split[1])
+	switch {
+	case err != nil || minor >= 17:
+		return layoutArgs
+	case minor >= 13:
+		return layoutJar
+	default:
+		return layoutUniversal
+	}
+}
+
+// serverJar returns the server jar of the older Forge layouts.
+func (this *Forge) serverJar() string {
+	if this.layout() == layoutUniversal {
+		return "forge-" + this.Version + "-universal.jar"
+	}
+	return "forge-" + this.Version + ".jar"
+}
+
+// serverFiles returns the files a server install of the Forge version
+// must provide.
+func (this *Forge) serverFiles() []string {
+	if this.layout() != layoutArgs {
+		return []string{this.serverJar()}
+	}
+	args, script := "unix_args.txt", "run.sh"
+	if runtime.GOOS == "windows" {
+		args, script = "win_args.txt", "run.bat"
+	}
+	return []string{filepath.Join(this.libraryDir(this.dist()), this.Version, args),
+		script, "user_jvm_args.txt"}
+}
+
+// libraryDir returns the directory of the distribution's artifacts in
+// the libraries tree.
+func (this *Forge) libraryDir(dist *Distribution) string {
+	return filepath.Join(LibraryDir, filepath.FromSlash(
+		strings.Replace(dist.Group, ".", "/", -1)), dist.Artifact)
+}
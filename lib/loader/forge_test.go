@@ -0,0 +1,136 @@
+package loader
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/plan"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeInstaller writes a modern Forge installer for the Forge version into
+// the working directory, with a single server processor producing the
+// given patched library.
+func writeInstaller(t *testing.T, f *Forge, patched string) {
+	profile := map[string]interface{}{
+		"minecraft": f.Minecraft(), "json": "/version.json",
+		"processors": []map[string]interface{}{{
+			"jar": "net.minecraftforge:installertools:1.0", "sides": []string{"server"},
+			"outputs": map[string]string{
+				"[net.minecraftforge:patched:1]": "'" + net.ByteChecksum([]byte(patched), sha1.New()) + "'",
+			},
+		}},
+	}
+	file, err := os.Create(f.Filename())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	w := zip.NewWriter(file)
+	for name, v := range map[string]interface{}{
+		"install_profile.json": profile, "version.json": map[string]string{"id": "forge"},
+	} {
+		data, _ := json.Marshal(v)
+		el, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		el.Write(data)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForgeUsesJava(t *testing.T) {
+	chdirTemp(t)
+	server, client := &Options{Server: true}, &Options{Client: true, MinecraftDir: "."}
+	legacy := &Forge{Version: "1.12.2-14.23.5.2860"}
+	if legacy.UsesJava(server) || legacy.UsesJava(client) {
+		t.Error("Forge 1.12.2 requires Java")
+	}
+	modern := &Forge{Version: "1.20.1-47.2.0"}
+	if !modern.UsesJava(server) {
+		t.Error("Forge 1.20.1 does not require Java without its installer")
+	}
+
+	writeInstaller(t, modern, "patched")
+	if !modern.UsesJava(server) {
+		t.Error("Forge 1.20.1 does not require Java with missing processor outputs")
+	}
+	if modern.UsesJava(client) {
+		t.Error("Forge 1.20.1 requires Java without client processors")
+	}
+	output := filepath.Join(LibraryDir, "net", "minecraftforge", "patched", "1", "patched-1.jar")
+	os.MkdirAll(filepath.Dir(output), 0755)
+	if err := ioutil.WriteFile(output, []byte("patched"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if modern.UsesJava(server) {
+		t.Error("Forge 1.20.1 requires Java with up to date processor outputs")
+	}
+}
+
+func TestForgeServerFiles(t *testing.T) {
+	args, script := "unix_args.txt", "run.sh"
+	if runtime.GOOS == "windows" {
+		args, script = "win_args.txt", "run.bat"
+	}
+	for _, el := range []struct {
+		Forge *Forge
+		Want  []string
+	}{
+		{&Forge{Version: "1.12.2-14.23.5.2860"}, []string{"forge-1.12.2-14.23.5.2860-universal.jar"}},
+		{&Forge{Version: "1.16.5-36.2.39"}, []string{"forge-1.16.5-36.2.39.jar"}},
+		{&Forge{Version: "1.20.1-47.2.0"}, []string{
+			filepath.Join(LibraryDir, "net", "minecraftforge", "forge", "1.20.1-47.2.0", args),
+			script, "user_jvm_args.txt"}},
+		{NewNeoForge("1.20.1-47.1.82", "", nil), []string{
+			filepath.Join(LibraryDir, "net", "neoforged", "forge", "1.20.1-47.1.82", args),
+			script, "user_jvm_args.txt"}},
+		{NewNeoForge("20.4.80-beta", "", nil), []string{
+			filepath.Join(LibraryDir, "net", "neoforged", "neoforge", "20.4.80-beta", args),
+			script, "user_jvm_args.txt"}},
+	} {
+		if got := el.Forge.serverFiles(); fmt.Sprint(got) != fmt.Sprint(el.Want) {
+			t.Errorf("%s: got server files %v, want %v", el.Forge, got, el.Want)
+		}
+	}
+}
+
+func TestForgePlanStaleVersions(t *testing.T) {
+	forge := &Forge{Version: "1.20.1-47.2.0"}
+	chdirTemp(t, forge.Filename(), "forge-1.16.5-36.2.39.jar", "forge-1.12.2-14.23.5.2860-universal.jar")
+	forge.SetChecksum(net.StringChecksum(forge.Filename(), net.DefaultHash()), net.DefaultHash())
+	libraries := filepath.Join(LibraryDir, "net", "minecraftforge", "forge")
+	neoforge := filepath.Join(LibraryDir, "net", "neoforged", "neoforge")
+	for _, el := range []string{filepath.Join(libraries, "1.20.1-47.1.0"), filepath.Join(libraries, forge.Version),
+		filepath.Join(neoforge, "20.4.80-beta"), filepath.Join(LibraryDir, "org", "ow2", "asm")} {
+		if err := os.MkdirAll(el, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"1.12.2-14.23.5.2860", "1.16.5-36.2.39", "1.20.1-47.1.0", forge.Version}
+	if got := forge.Installed(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got installed versions %v, want %v", got, want)
+	}
+
+	// Shared libraries and the current version are kept
+	p, err := forge.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeleted(t, p, "forge-1.12.2-14.23.5.2860-universal.jar", "forge-1.16.5-36.2.39.jar",
+		filepath.Join(libraries, "1.20.1-47.1.0"), neoforge)
+	for _, el := range p {
+		if el.Op == plan.Download {
+			t.Errorf("valid installer %s downloaded again", el.Path)
+		}
+	}
+}
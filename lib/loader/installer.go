@@ -0,0 +1,474 @@
+package loader
+
+import (
+	"archive/zip"
+	"bufio"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/maven"
+	"github.com/faceless-saint/m3/lib/net"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// forgeInstaller values represent opened Forge installer jars. Both the
+// legacy installer format (Minecraft 1.12 and older) and the modern
+// format with install processors are supported.
+type forgeInstaller struct {
+	file    string
+	zip     *zip.ReadCloser
+	Profile installProfile
+	// Version is the launcher version manifest provided by the installer.
+	Version versionManifest
+	// versionData is the raw JSON data of Version.
+	versionData []byte
+}
+
+// installProfile values act as JSON import containers for the
+// install_profile.json of Forge installers.
+type installProfile struct {
+	Version       string
+	Json          string
+	Path          string
+	Minecraft     string
+	ServerJarPath string
+	Data          map[string]struct{ Client, Server string }
+	Processors    []processor
+	Libraries     []RawLibrary
+	// Install and VersionInfo are used by legacy installers.
+	Install *struct {
+		Path      string
+		FilePath  string
+		Minecraft string
+	}
+	VersionInfo json.RawMessage
+}
+
+// versionManifest values act as JSON import containers for launcher
+// version manifests.
+type versionManifest struct {
+	Id        string
+	Libraries []RawLibrary
+}
+
+// processor values act as JSON import containers for the processors of
+// modern Forge installers, which are Java programs run to generate the
+// patched game files.
+type processor struct {
+	Jar       string
+	Sides     []string
+	Classpath []string
+	Args      []string
+	Outputs   map[string]string
+}
+
+// openInstaller opens the given Forge installer jar and reads its
+// install profile and version manifest.
+func openInstaller(file string) (*forgeInstaller, error) {
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	this := forgeInstaller{file: file, zip: z}
+	data, err := this.read("install_profile.json")
+	if err == nil {
+		err = json.Unmarshal(data, &this.Profile)
+	}
+	if err == nil {
+		if this.legacy() {
+			this.versionData = this.Profile.VersionInfo
+		} else {
+			this.versionData, err = this.read(strings.TrimPrefix(this.Profile.Json, "/"))
+		}
+	}
+	if err == nil {
+		err = json.Unmarshal(this.versionData, &this.Version)
+	}
+	if err != nil {
+		z.Close()
+		return nil, fmt.Errorf("error: invalid forge installer %s: %v", file, err)
+	}
+	return &this, nil
+}
+
+func (this *forgeInstaller) Close() error { return this.zip.Close() }
+
+// legacy returns true iff the installer uses the legacy format.
+func (this *forgeInstaller) legacy() bool { return this.Profile.Install != nil }
+
+// minecraft returns the Minecraft version targeted by the installer.
+func (this *forgeInstaller) minecraft() string {
+	if this.legacy() {
+		return this.Profile.Install.Minecraft
+	}
+	return this.Profile.Minecraft
+}
+
+// libraries returns the libraries required on the given side, either
+// "client" or "server".
+func (this *forgeInstaller) libraries(side string) ([]*Library, error) {
+	raw := append([]RawLibrary{}, this.Version.Libraries...)
+	if !this.legacy() {
+		raw = append(raw, this.Profile.Libraries...)
+	}
+	libs, seen := []*Library{}, map[string]*struct{}{}
+	for i, el := range raw {
+		if this.legacy() {
+			// Legacy installers mark the libraries they must provide,
+			// and bundle the Forge library itself
+			req := el.Clientreq
+			if side == "server" {
+				req = el.Serverreq
+			}
+			if req == nil || !*req || el.Name == this.Profile.Install.Path {
+				continue
+			}
+		}
+		lib, err := NewLibrary(&raw[i])
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[lib.Filename()]; !ok {
+			seen[lib.Filename()] = new(struct{})
+			libs = append(libs, lib)
+		}
+	}
+	return libs, nil
+}
+
+// installLibraries installs the libraries required on the given side
+// into the directory 'dir'. Bundled libraries are extracted from the
+// installer, and all others are downloaded and verified.
+func (this *forgeInstaller) installLibraries(dir, side string, opts *Options) error {
+	libs, err := this.libraries(side)
+	if err != nil {
+		return err
+	}
+	remote := []*Library{}
+	for _, el := range libs {
+		if el.Url() != "" {
+			remote = append(remote, el)
+			continue
+		}
+		name, file := "maven/"+filepath.ToSlash(el.Filename()), filepath.Join(dir, el.Filename())
+		if err := opts.preserve(file); err != nil {
+			return err
+		}
+		if err := this.extract(name, file); err != nil {
+			return err
+		}
+		if err := verify(el, file); err != nil {
+			return err
+		}
+	}
+	if this.legacy() {
+		c, err := maven.Parse(this.Profile.Install.Path)
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(dir, filepath.FromSlash(c.Path()))
+		if err := opts.preserve(file); err != nil {
+			return err
+		}
+		if err := this.extract(this.Profile.Install.FilePath, file); err != nil {
+			return err
+		}
+	}
+	return getLibraries(dir, remote, opts)
+}
+
+// process runs the installer processors for the given side that have
+// not already produced their outputs, using the Java executable of the
+// Options. 'root' is the installation root, 'libDir' its library
+// directory, and 'minecraftJar' the vanilla jar of that side.
+func (this *forgeInstaller) process(side, root, libDir, minecraftJar string, opts *Options) error {
+	tmp, err := ioutil.TempDir("", "m3-forge")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	data, err := this.data(side, root, libDir, minecraftJar, tmp)
+	if err != nil {
+		return err
+	}
+	for _, proc := range this.Profile.Processors {
+		if !proc.runsOn(side) {
+			continue
+		}
+		outputs, err := proc.outputs(data, libDir)
+		if err != nil {
+			return err
+		}
+		if len(outputs) > 0 && checkOutputs(outputs) == nil {
+			// Outputs are already up to date
+			continue
+		}
+		for file := range outputs {
+			if err := opts.preserve(file); err != nil {
+				return err
+			}
+		}
+		jar := libraryPath(libDir, proc.Jar)
+		main, err := mainClass(jar)
+		if err != nil {
+			return err
+		}
+		classpath := []string{jar}
+		for _, el := range proc.Classpath {
+			classpath = append(classpath, libraryPath(libDir, el))
+		}
+		args := []string{"-cp", strings.Join(classpath, string(os.PathListSeparator)), main}
+		for _, el := range proc.Args {
+			arg, err := resolveArg(el, data, libDir)
+			if err != nil {
+				return err
+			}
+			args = append(args, arg)
+		}
+		cmd := exec.Command(opts.java(), args...)
+		cmd.Dir = root
+		if opts.Verbose {
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+		}
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("error: forge processor %s failed: %v", proc.Jar, err)
+		}
+		if err := checkOutputs(outputs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pending returns true iff any installer processor for the given side
+// would be run by process, taking the same arguments.
+func (this *forgeInstaller) pending(side, root, libDir, minecraftJar string) (bool, error) {
+	tmp, err := ioutil.TempDir("", "m3-forge")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(tmp)
+	data, err := this.data(side, root, libDir, minecraftJar, tmp)
+	if err != nil {
+		return false, err
+	}
+	for _, proc := range this.Profile.Processors {
+		if !proc.runsOn(side) {
+			continue
+		}
+		outputs, err := proc.outputs(data, libDir)
+		if err != nil {
+			return false, err
+		}
+		if len(outputs) == 0 || checkOutputs(outputs) != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// data returns the installer data values for the given side, including
+// the built-in values. Files referenced by the data are extracted into
+// the directory 'tmp'.
+func (this *forgeInstaller) data(side, root, libDir, minecraftJar, tmp string) (map[string]string, error) {
+	installer, err := filepath.Abs(this.file)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{
+		"SIDE":              side,
+		"ROOT":              root,
+		"INSTALLER":         installer,
+		"LIBRARY_DIR":       libDir,
+		"MINECRAFT_JAR":     minecraftJar,
+		"MINECRAFT_VERSION": this.minecraft(),
+	}
+	for key, el := range this.Profile.Data {
+		value := el.Client
+		if side == "server" {
+			value = el.Server
+		}
+		switch {
+		case strings.HasPrefix(value, "["), strings.HasPrefix(value, "'"):
+			if value, err = resolveArg(value, nil, libDir); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(value, "/"):
+			file := filepath.Join(tmp, filepath.FromSlash(value))
+			if err := this.extract(value[1:], file); err != nil {
+				return nil, err
+			}
+			value = file
+		}
+		data[key] = value
+	}
+	return data, nil
+}
+
+// serverJar returns the path of the vanilla server jar used by the
+// installer, given the installation root and its library directory.
+func (this *forgeInstaller) serverJar(root, libDir string) string {
+	if this.Profile.ServerJarPath == "" {
+		return filepath.Join(root, "minecraft_server."+this.minecraft()+".jar")
+	}
+	return strings.NewReplacer("{LIBRARY_DIR}", libDir, "{MINECRAFT_VERSION}", this.minecraft()).
+		Replace(filepath.FromSlash(this.Profile.ServerJarPath))
+}
+
+// has returns true iff the named file exists in the installer.
+func (this *forgeInstaller) has(name string) bool {
+	for _, el := range this.zip.File {
+		if el.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// read returns the contents of the named file in the installer.
+func (this *forgeInstaller) read(name string) ([]byte, error) {
+	for _, el := range this.zip.File {
+		if el.Name == name {
+			r, err := el.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return ioutil.ReadAll(r)
+		}
+	}
+	return nil, fmt.Errorf("error: %s not found in %s", name, this.file)
+}
+
+// extract saves the named file in the installer to the given path.
+func (this *forgeInstaller) extract(name, file string) error {
+	for _, el := range this.zip.File {
+		if el.Name != name {
+			continue
+		}
+		r, err := el.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		w, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}
+	return fmt.Errorf("error: %s not found in %s", name, this.file)
+}
+
+// runsOn returns true iff the processor runs on the given side.
+func (this *processor) runsOn(side string) bool {
+	if len(this.Sides) == 0 {
+		return true
+	}
+	for _, el := range this.Sides {
+		if el == side {
+			return true
+		}
+	}
+	return false
+}
+
+// outputs returns the SHA1 checksums of the processor's output files, by
+// path, resolved with the given installer data values.
+func (this *processor) outputs(data map[string]string, libDir string) (map[string]string, error) {
+	outputs := map[string]string{}
+	for key, value := range this.Outputs {
+		key, err := resolveArg(key, data, libDir)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = resolveArg(value, data, libDir); err != nil {
+			return nil, err
+		}
+		outputs[key] = value
+	}
+	return outputs, nil
+}
+
+// resolveArg resolves an installer argument. Arguments of the form
+// "{KEY}" are replaced by data values, "[coordinate]" by library paths
+// and "'literal'" by the quoted literal.
+func resolveArg(arg string, data map[string]string, libDir string) (string, error) {
+	switch {
+	case len(arg) > 1 && arg[0] == '{' && arg[len(arg)-1] == '}':
+		value, ok := data[arg[1:len(arg)-1]]
+		if !ok {
+			return "", fmt.Errorf("error: unknown forge installer value %s", arg)
+		}
+		return value, nil
+	case len(arg) > 1 && arg[0] == '[' && arg[len(arg)-1] == ']':
+		return libraryPath(libDir, arg[1:len(arg)-1]), nil
+	case len(arg) > 1 && arg[0] == '\'' && arg[len(arg)-1] == '\'':
+		return arg[1 : len(arg)-1], nil
+	}
+	return arg, nil
+}
+
+// libraryPath returns the path of the library with the given Maven
+// coordinate in the directory 'libDir'.
+func libraryPath(libDir, coordinate string) string {
+	c, err := maven.Parse(coordinate)
+	if err != nil {
+		return filepath.Join(libDir, coordinate)
+	}
+	return filepath.Join(libDir, filepath.FromSlash(c.Path()))
+}
+
+// checkOutputs verifies that every output file exists and matches its
+// SHA1 checksum.
+func checkOutputs(outputs map[string]string) error {
+	for file, checksum := range outputs {
+		ok, err := net.CheckFile(file, checksum, sha1.New())
+		if err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("error: checksum mismatch for %s", file)
+		}
+	}
+	return nil
+}
+
+// mainClass returns the Main-Class of the given jar's manifest.
+func mainClass(jar string) (string, error) {
+	z, err := zip.OpenReader(jar)
+	if err != nil {
+		return "", err
+	}
+	defer z.Close()
+	for _, el := range z.File {
+		if el.Name != "META-INF/MANIFEST.MF" {
+			continue
+		}
+		r, err := el.Open()
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "Main-Class:") {
+				return strings.TrimSpace(strings.TrimPrefix(line, "Main-Class:")), nil
+			}
+		}
+		return "", scanner.Err()
+	}
+	return "", fmt.Errorf("error: no Main-Class found in %s", jar)
+}
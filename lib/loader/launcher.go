@@ -0,0 +1,102 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// LauncherProfiles is the name of the launcher's profile list.
+const LauncherProfiles = "launcher_profiles.json"
+
+// DefaultMinecraftDir returns the .minecraft directory used by the
+// official launcher on the current platform.
+func DefaultMinecraftDir() string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("APPDATA"), ".minecraft")
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "minecraft")
+	default:
+		return filepath.Join(home, ".minecraft")
+	}
+}
+
+// InstallVersion saves the given version manifest to the versions
+// directory of the launcher in 'dir'.
+func InstallVersion(dir, id string, data []byte) error {
+	file := versionFile(dir, id)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// versionFile returns the path of the version manifest with the given ID
+// in the launcher in 'dir'.
+func versionFile(dir, id string) string {
+	return filepath.Join(dir, "versions", id, id+".json")
+}
+
+// addVersion installs the version manifest into the launcher in
+// opts.MinecraftDir, along with a launcher profile running it, preserving
+// the files it changes.
+func addVersion(opts *Options, id string, data []byte) error {
+	dir := opts.MinecraftDir
+	if err := opts.preserve(versionFile(dir, id), filepath.Join(dir, LauncherProfiles)); err != nil {
+		return err
+	}
+	if err := InstallVersion(dir, id, data); err != nil {
+		return err
+	}
+	return AddProfile(dir, opts.Profile, id, opts.GameDir)
+}
+
+// AddProfile adds a custom profile running the given version to the
+// launcher in 'dir', using 'gameDir' as the game directory. Profiles are
+// identified by their game directory, so installing the same modpack
+// again updates its existing profile. All other launcher settings are
+// preserved.
+func AddProfile(dir, name, version, gameDir string) error {
+	file := filepath.Join(dir, LauncherProfiles)
+	root := map[string]interface{}{}
+	if data, err := ioutil.ReadFile(file); err == nil {
+		if err := json.Unmarshal(data, &root); err != nil {
+			return fmt.Errorf("error: invalid launcher profiles %s: %v", file, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	profiles, ok := root["profiles"].(map[string]interface{})
+	if !ok {
+		profiles = map[string]interface{}{}
+		root["profiles"] = profiles
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	key := "m3-" + net.Digest(gameDir, 12)
+	profile, ok := profiles[key].(map[string]interface{})
+	if !ok {
+		profile = map[string]interface{}{"created": now, "icon": "Furnace"}
+		profiles[key] = profile
+	}
+	profile["name"] = name
+	profile["type"] = "custom"
+	profile["lastVersionId"] = version
+	profile["gameDir"] = gameDir
+	profile["lastUsed"] = now
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
@@ -0,0 +1,91 @@
+package loader
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// readProfiles returns the launcher profiles in the directory.
+func readProfiles(t *testing.T, dir string) map[string]interface{} {
+	data, err := ioutil.ReadFile(filepath.Join(dir, LauncherProfiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := map[string]interface{}{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestAddProfile(t *testing.T) {
+	dir := t.TempDir()
+	existing := `{"profiles": {"vanilla": {"name": "Latest", "type": "latest-release"}},
+		"settings": {"keepLauncherOpen": true}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, LauncherProfiles), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddProfile(dir, "Pack", "1.20.1-forge-47.1.0", "/srv/pack"); err != nil {
+		t.Fatal(err)
+	}
+	// Installing the same modpack again updates its profile, keeping the
+	// settings changed in the launcher
+	root := readProfiles(t, dir)
+	for _, el := range root["profiles"].(map[string]interface{}) {
+		el.(map[string]interface{})["icon"] = "Grass"
+	}
+	data, _ := json.Marshal(root)
+	if err := ioutil.WriteFile(filepath.Join(dir, LauncherProfiles), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddProfile(dir, "Pack 2", "1.20.1-forge-47.2.0", "/srv/pack"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddProfile(dir, "Other", "1.20.1-forge-47.2.0", "/srv/other"); err != nil {
+		t.Fatal(err)
+	}
+	root = readProfiles(t, dir)
+	if settings, ok := root["settings"].(map[string]interface{}); !ok || settings["keepLauncherOpen"] != true {
+		t.Errorf("launcher settings not preserved: %v", root["settings"])
+	}
+	profiles := root["profiles"].(map[string]interface{})
+	if len(profiles) != 3 || profiles["vanilla"] == nil {
+		t.Fatalf("got profiles %v, want vanilla and two modpacks", profiles)
+	}
+	for _, el := range profiles {
+		profile := el.(map[string]interface{})
+		if profile["gameDir"] != "/srv/pack" {
+			continue
+		}
+		if profile["name"] != "Pack 2" || profile["lastVersionId"] != "1.20.1-forge-47.2.0" || profile["type"] != "custom" {
+			t.Errorf("got profile %v, want the updated modpack profile", profile)
+		}
+		if profile["icon"] != "Grass" {
+			t.Errorf("got profile %v, want the existing profile updated", profile)
+		}
+	}
+}
+
+func TestAddProfileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, LauncherProfiles), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddProfile(dir, "Pack", "1.20.1-forge-47.2.0", "/srv/pack"); err == nil {
+		t.Error("invalid launcher profiles overwritten")
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, LauncherProfiles)); string(data) != "{" {
+		t.Errorf("invalid launcher profiles changed to %s", data)
+	}
+
+	// A missing launcher directory is created
+	dir = filepath.Join(t.TempDir(), ".minecraft")
+	if err := AddProfile(dir, "Pack", "1.20.1-forge-47.2.0", "/srv/pack"); err != nil {
+		t.Fatal(err)
+	}
+	if profiles := readProfiles(t, dir)["profiles"].(map[string]interface{}); len(profiles) != 1 {
+		t.Errorf("got profiles %v", profiles)
+	}
+}
@@ -0,0 +1,165 @@
+package loader
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/maven"
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/output"
+	"hash"
+	"os"
+	"path/filepath"
+)
+
+// LibraryDir is the directory loader libraries are installed into.
+const LibraryDir = "libraries"
+
+// MojangLibraries is the default repository for libraries that do not
+// name one.
+const MojangLibraries = "https://libraries.minecraft.net"
+
+// Library values represent Maven artifacts required by a loader. The
+// Filename is the path of the artifact within LibraryDir.
+type Library struct {
+	Coordinate maven.Coordinate
+	// Repository is the URL of the Maven repository hosting the library.
+	Repository string
+	checksum   string
+	// path and url override the location of the artifact if set.
+	path string
+	url  string
+}
+
+// RawLibrary values act as JSON import containers for the libraries
+// listed in loader profiles and launcher version manifests.
+type RawLibrary struct {
+	Name string
+	// Url is the Maven repository of the library.
+	Url  string
+	Sha1 string
+	// Downloads gives the exact location of the library, as used by
+	// launcher version manifests.
+	Downloads struct{ Artifact Artifact }
+	// Checksums, Clientreq and Serverreq are used by legacy Forge
+	// installers.
+	Checksums []string
+	Clientreq *bool
+	Serverreq *bool
+}
+
+// Artifact values describe a file download in launcher version manifests.
+type Artifact struct {
+	Path string
+	Url  string
+	Sha1 string
+	Size int64
+}
+
+// NewLibrary returns a new Library from the given RawLibrary.
+func NewLibrary(raw *RawLibrary) (*Library, error) {
+	c, err := maven.Parse(raw.Name)
+	if err != nil {
+		return nil, err
+	}
+	this := Library{Coordinate: *c, Repository: raw.Url, checksum: raw.Sha1}
+	if a := raw.Downloads.Artifact; a.Path != "" {
+		this.path, this.url, this.checksum = a.Path, a.Url, a.Sha1
+	} else if this.Repository == "" {
+		this.Repository = MojangLibraries
+	}
+	if this.checksum == "" && len(raw.Checksums) > 0 {
+		this.checksum = raw.Checksums[0]
+	}
+	return &this, nil
+}
+
+// Url returns the download URL of the library, or an empty string if
+// the library is bundled with its installer.
+func (this *Library) Url() string {
+	if this.path != "" {
+		return this.url
+	}
+	return this.Coordinate.Url(this.Repository)
+}
+
+func (this *Library) Filename() string {
+	if this.path != "" {
+		return filepath.FromSlash(this.path)
+	}
+	return filepath.FromSlash(this.Coordinate.Path())
+}
+func (this *Library) Checksum() string { return this.checksum }
+func (this *Library) Hash() hash.Hash  { return sha1.New() }
+
+// getLibraries downloads the given libraries into the directory 'dir',
+// using at most opts.Concurrency simultaneous downloads, and verifies them.
+// Existing files failing checksum validation are downloaded again.
+func getLibraries(dir string, libs []*Library, opts *Options) error {
+	items := net.Downloadables{}
+	for _, el := range libs {
+		file := filepath.Join(dir, el.Filename())
+		if _, err := os.Stat(file); err == nil {
+			if ok, err := net.CheckFile(file, el.Checksum(), el.Hash()); err != nil {
+				return err
+			} else if ok {
+				items = append(items, el)
+				continue
+			}
+		}
+		if err := opts.preserve(file); err != nil {
+			return err
+		}
+		os.Remove(file)
+		items = append(items, el)
+	}
+	respch, count, err := items.GetFiles(dir, opts.Concurrency)
+	if err != nil {
+		return err
+	}
+	tracker := output.DownloadTracker{Name: "libraries", Channel: respch,
+		Interval: 200, Count: count, Total: len(items), Dir: dir}
+	tracker.Log()
+	if failed := tracker.Failed(); len(failed) > 0 {
+		return fmt.Errorf("error: %d libraries failed to download", len(failed))
+	}
+	for _, el := range libs {
+		if err := verify(el, filepath.Join(dir, el.Filename())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch downloads a single file to the given path and verifies it. Valid
+// existing files are kept.
+func fetch(dl net.Downloadable, file string, opts *Options) error {
+	if _, err := os.Stat(file); err == nil {
+		if ok, err := net.CheckFile(file, dl.Checksum(), dl.Hash()); err != nil {
+			return err
+		} else if ok {
+			return nil
+		}
+	}
+	if err := opts.preserve(file); err != nil {
+		return err
+	}
+	os.Remove(file)
+	resp, err := net.GetFile(dl, file)
+	if err != nil {
+		return err
+	} else if resp != nil && resp.Error != nil {
+		return resp.Error
+	}
+	return verify(dl, file)
+}
+
+// verify checks a file against the Downloadable's reference checksum.
+func verify(dl net.Downloadable, file string) error {
+	ok, err := net.CheckFile(file, dl.Checksum(), dl.Hash())
+	if err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("error: checksum mismatch for %s", file)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+/* Loader is a library for installing Minecraft mod loaders, such as Forge
+ * and Fabric. Each loader provides an installer file for the working
+ * directory, along with the steps to install server files or a client
+ * launcher profile from it.
+ */
+package loader
+
+import (
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/plan"
+	"hash"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Loader is the interface for mod loaders. The Downloadable file is the
+// loader's installer, which is kept in the working directory.
+type Loader interface {
+	net.Downloadable
+	// Name returns the loader type, such as "forge".
+	Name() string
+	// Minecraft returns the Minecraft version targeted by the loader.
+	Minecraft() string
+	// String returns the loader type and version for display.
+	String() string
+	// Plan computes the changes needed to prepare the working directory
+	// for the loader, without modifying it.
+	Plan() (plan.Plan, error)
+	// Install installs the loader from its installer file.
+	Install(opts *Options) error
+	// SetChecksum replaces the reference checksum of the installer.
+	SetChecksum(checksum string, h hash.Hash)
+}
+
+// Verifier is the interface for Loaders that can verify their installed
+// server files.
+type Verifier interface {
+	// Verify checks that the server files are installed.
+	Verify() error
+}
+
+// JavaUser is the interface for Loaders whose installation may run Java.
+type JavaUser interface {
+	// UsesJava returns true iff installing the loader with the given
+	// Options runs Java.
+	UsesJava(opts *Options) bool
+}
+
+// Provider is the interface for Loaders that mods may declare dependencies
+// on by mod ID.
+type Provider interface {
+	// Provides returns the versions of the mod IDs provided by the loader,
+	// including "minecraft".
+	Provides() map[string]string
+}
+
+// Raw values act as JSON import containers for Loader values.
+type Raw struct {
+	// Type is the loader type: "forge" (default), "neoforge" or "fabric".
+	Type string
+	// Minecraft is the Minecraft version, if not implied by Version.
+	Minecraft string
+	// Version is the loader version.
+	Version        string
+	Checksum       string
+	ServerChecksum string
+}
+
+// Options values describe which installation steps to perform.
+type Options struct {
+	// Server installs the server files into the working directory.
+	Server bool
+	// Client installs a launcher profile into MinecraftDir.
+	Client bool
+	// MinecraftDir is the launcher's .minecraft directory.
+	MinecraftDir string
+	// GameDir is the game directory of the launcher profile.
+	GameDir string
+	// Profile is the name of the launcher profile.
+	Profile string
+	// Concurrency is the maximum number of simultaneous downloads.
+	Concurrency int
+	// Java is the Java executable used by the installation. Defaults to
+	// "java".
+	Java    string
+	Verbose bool
+	// Preserve, if set, is called with each file before the installation
+	// changes it, so that the change can be rolled back.
+	Preserve func(file string) error
+}
+
+// java returns the Java executable of the Options.
+func (this *Options) java() string {
+	if this.Java == "" {
+		return "java"
+	}
+	return this.Java
+}
+
+// preserve calls the Preserve function of the Options for each file.
+func (this *Options) preserve(files ...string) error {
+	if this.Preserve == nil {
+		return nil
+	}
+	for _, el := range files {
+		if err := this.Preserve(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// New returns a new Loader from the given Raw value, or nil if no loader
+// version is given.
+func New(raw *Raw) (Loader, error) {
+	if raw.Version == "" && raw.Minecraft == "" {
+		return nil, nil
+	}
+	checksum, h, err := parseChecksum(raw.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(raw.Type) {
+	case "", "forge":
+		return &Forge{raw.Version, checksum, h, raw.ServerChecksum, &MinecraftForge}, nil
+	case "neoforge":
+		return NewNeoForge(raw.Version, checksum, h), nil
+	case "fabric":
+		return NewFabric(raw.Minecraft, raw.Version, checksum, h)
+	default:
+		return nil, fmt.Errorf("error: unknown loader type %q - need one of 'forge', 'neoforge', 'fabric'", raw.Type)
+	}
+}
+
+// parseChecksum splits a checksum of the form "[algo:]checksum" into the
+// checksum and its hashing algorithm, which defaults to SHA256.
+func parseChecksum(s string) (string, hash.Hash, error) {
+	hashsum := strings.SplitN(s, ":", 2)
+	if len(hashsum) < 2 {
+		hashsum = []string{"sha256", hashsum[0]}
+	}
+	h, err := net.NewHash(hashsum[0])
+	return hashsum[1], h, err
+}
+
+// files lists the glob patterns of the files each loader type keeps in
+// the working directory. The first pattern matches the installer file.
+var files = []struct {
+	Name     string
+	Patterns []string
+}{
+	{"forge", []string{"forge-*-installer.jar", "forge-*-universal.jar"}},
+	{"neoforge", []string{"neoforge-*-installer.jar"}},
+	{"fabric", []string{"fabric-server-mc.*-launcher.*.jar"}},
+}
+
+// clean plans the removal of the files of every other loader type, and
+// of stale or invalid installers of the given loader. Returns true iff a
+// valid copy of the current installer is present.
+func clean(p *plan.Plan, l Loader) (bool, error) {
+	valid := false
+	for _, el := range files {
+		if el.Name != l.Name() {
+			for _, pattern := range el.Patterns {
+				matches, _ := filepath.Glob(pattern)
+				for _, file := range matches {
+					if file != l.Filename() {
+						p.Add(plan.Delete, file)
+					}
+				}
+			}
+			continue
+		}
+		installers, _ := filepath.Glob(el.Patterns[0])
+		if ok, _ := filepath.Match(el.Patterns[0], l.Filename()); !ok {
+			// The installer is named differently, as for legacy NeoForge
+			if _, err := os.Stat(l.Filename()); err == nil {
+				installers = append(installers, l.Filename())
+			}
+		}
+		for _, file := range installers {
+			if file != l.Filename() {
+				p.Add(plan.Delete, file)
+				continue
+			}
+			match, err := net.CheckFile(file, l.Checksum(), l.Hash())
+			if err != nil {
+				return false, err
+			}
+			if match {
+				valid = true
+			} else {
+				p.Add(plan.Delete, file)
+			}
+		}
+	}
+	return valid, nil
+}
+
+// download plans the download of the loader's installer.
+func download(p *plan.Plan, l Loader) {
+	*p = append(*p, plan.Action{Op: plan.Download, Path: l.Filename(), Url: l.Url()})
+}
@@ -0,0 +1,112 @@
+package loader
+
+import (
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/plan"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+)
+
+// chdirTemp changes into a new temporary directory holding the given
+// files, for the duration of the test.
+func chdirTemp(t *testing.T, files ...string) {
+	dir, err := ioutil.TempDir("", "m3-loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(original)
+		os.RemoveAll(dir)
+	})
+	for _, el := range files {
+		if err := ioutil.WriteFile(el, []byte(el), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// deleted returns the sorted paths deleted by the plan.
+func deleted(p plan.Plan) []string {
+	paths := []string{}
+	for _, el := range p {
+		if el.Op == plan.Delete {
+			paths = append(paths, el.Path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func checkDeleted(t *testing.T, p plan.Plan, want ...string) {
+	got := deleted(p)
+	if len(got) != len(want) {
+		t.Fatalf("deleted %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("deleted %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCleanFabric(t *testing.T) {
+	fabric := &Fabric{Game: "1.20.1", Loader: "0.15.3", InstallerVersion: "1.0.0"}
+	current := fabric.Filename()
+	stale := "fabric-server-mc.1.20.1-loader.0.14.0-launcher.1.0.0.jar"
+	forge := "forge-1.20.1-47.2.0-installer.jar"
+	chdirTemp(t, current, stale, forge, "unrelated.jar")
+	fabric.SetChecksum(net.StringChecksum(current, net.DefaultHash()), net.DefaultHash())
+
+	p := plan.Plan{}
+	valid, err := clean(&p, fabric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Errorf("current launcher %s not found valid", current)
+	}
+	checkDeleted(t, p, stale, forge)
+}
+
+func TestCleanInvalidInstaller(t *testing.T) {
+	fabric := &Fabric{Game: "1.20.1", Loader: "0.15.3", InstallerVersion: "1.0.0"}
+	chdirTemp(t, fabric.Filename())
+	fabric.SetChecksum(net.StringChecksum("other", net.DefaultHash()), net.DefaultHash())
+
+	p := plan.Plan{}
+	valid, err := clean(&p, fabric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("launcher with a mismatched checksum found valid")
+	}
+	checkDeleted(t, p, fabric.Filename())
+}
+
+func TestCleanOtherLoaders(t *testing.T) {
+	forge := &Forge{Version: "1.20.1-47.2.0"}
+	launcher := "fabric-server-mc.1.20.1-loader.0.15.3-launcher.1.0.0.jar"
+	neoforge := "neoforge-20.4.80-beta-installer.jar"
+	chdirTemp(t, launcher, neoforge, forge.Filename())
+	forge.SetChecksum(net.StringChecksum(forge.Filename(), net.DefaultHash()), net.DefaultHash())
+
+	p := plan.Plan{}
+	valid, err := clean(&p, forge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Errorf("current installer %s not found valid", forge.Filename())
+	}
+	checkDeleted(t, p, launcher, neoforge)
+}
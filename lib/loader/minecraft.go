@@ -0,0 +1,89 @@
+package loader
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"hash"
+	"path/filepath"
+)
+
+// VersionManifest is the URL of Mojang's Minecraft version manifest.
+var VersionManifest = "https://piston-meta.mojang.com/mc/game/version_manifest_v2.json"
+
+// minecraftVersion values act as JSON import containers for Minecraft
+// version manifests.
+type minecraftVersion struct {
+	Id        string
+	Downloads struct {
+		Client Artifact
+		Server Artifact
+	}
+}
+
+// getMinecraft returns the version manifest of the given Minecraft
+// version, along with its raw JSON data.
+func getMinecraft(version string) (*minecraftVersion, []byte, error) {
+	var manifest struct {
+		Versions []struct{ Id, Url string }
+	}
+	if err := net.GetJSON(VersionManifest, nil, &manifest); err != nil {
+		return nil, nil, err
+	}
+	for _, el := range manifest.Versions {
+		if el.Id != version {
+			continue
+		}
+		data, err := net.Get(el.Url, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		var this minecraftVersion
+		if err := json.Unmarshal(data, &this); err != nil {
+			return nil, nil, fmt.Errorf("error: invalid manifest for minecraft %s: %v", version, err)
+		}
+		return &this, data, nil
+	}
+	return nil, nil, fmt.Errorf("error: unknown minecraft version %q", version)
+}
+
+// installMinecraft installs the vanilla client of the given Minecraft
+// version into the launcher in 'dir', and returns the path of its jar.
+func installMinecraft(dir, version string, opts *Options) (string, error) {
+	mc, data, err := getMinecraft(version)
+	if err != nil {
+		return "", err
+	}
+	if err := opts.preserve(versionFile(dir, mc.Id)); err != nil {
+		return "", err
+	}
+	if err := InstallVersion(dir, mc.Id, data); err != nil {
+		return "", err
+	}
+	jar := filepath.Join(dir, "versions", mc.Id, mc.Id+".jar")
+	return jar, fetch(&artifactFile{mc.Downloads.Client}, jar, opts)
+}
+
+// installMinecraftServer downloads the vanilla server jar of the given
+// Minecraft version to the given path.
+func installMinecraftServer(version, file string, opts *Options) error {
+	mc, _, err := getMinecraft(version)
+	if err != nil {
+		return err
+	}
+	if mc.Downloads.Server.Url == "" {
+		return fmt.Errorf("error: no server available for minecraft %s", version)
+	}
+	return fetch(&artifactFile{mc.Downloads.Server}, file, opts)
+}
+
+// artifactFile values implement net.Downloadable for Artifacts.
+type artifactFile struct {
+	Artifact
+}
+
+func (this *artifactFile) Url() string      { return this.Artifact.Url }
+func (this *artifactFile) Filename() string { return filepath.FromSlash(this.Path) }
+func (this *artifactFile) Checksum() string { return this.Sha1 }
+func (this *artifactFile) Hash() hash.Hash  { return sha1.New() }
@@ -0,0 +1,164 @@
+/* Maven is a library for resolving artifacts hosted in Maven repositories,
+ * including version resolution through maven-metadata.xml and checksum
+ * retrieval from sidecar files.
+ */
+package maven
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"strings"
+)
+
+// Central is the URL of the Maven Central repository.
+const Central = "https://repo1.maven.org/maven2"
+
+// Special versions resolved through the repository metadata.
+const (
+	Latest  = "latest"
+	Release = "release"
+)
+
+// Coordinate values identify a single Maven artifact.
+type Coordinate struct {
+	Group      string
+	Artifact   string
+	Version    string
+	Classifier string
+	// Extension is the artifact file extension. Defaults to "jar".
+	Extension string
+}
+
+// Parse returns a new Coordinate from a string of the form
+// "group:artifact:version[:classifier][@extension]".
+func Parse(coordinate string) (*Coordinate, error) {
+	this := Coordinate{Extension: "jar"}
+	if split := strings.SplitN(coordinate, "@", 2); len(split) == 2 {
+		coordinate, this.Extension = split[0], split[1]
+	}
+	split := strings.Split(coordinate, ":")
+	if len(split) < 3 || len(split) > 4 {
+		return nil, fmt.Errorf("error: invalid maven coordinate %q", coordinate)
+	}
+	this.Group, this.Artifact, this.Version = split[0], split[1], split[2]
+	if len(split) == 4 {
+		this.Classifier = split[3]
+	}
+	for _, el := range split {
+		if el == "" {
+			return nil, fmt.Errorf("error: invalid maven coordinate %q", coordinate)
+		}
+	}
+	return &this, nil
+}
+
+func (this *Coordinate) String() string {
+	s := this.Group + ":" + this.Artifact + ":" + this.Version
+	if this.Classifier != "" {
+		s += ":" + this.Classifier
+	}
+	if this.Extension != "" && this.Extension != "jar" {
+		s += "@" + this.Extension
+	}
+	return s
+}
+
+// Filename returns the file name of the artifact in the repository.
+func (this *Coordinate) Filename() string {
+	name := this.Artifact + "-" + this.Version
+	if this.Classifier != "" {
+		name += "-" + this.Classifier
+	}
+	ext := this.Extension
+	if ext == "" {
+		ext = "jar"
+	}
+	return name + "." + ext
+}
+
+// Path returns the path of the artifact relative to the repository root.
+func (this *Coordinate) Path() string {
+	return this.dir() + "/" + this.Version + "/" + this.Filename()
+}
+
+// Url returns the download URL of the artifact in the given repository.
+func (this *Coordinate) Url(repository string) string {
+	return strings.TrimSuffix(repository, "/") + "/" + this.Path()
+}
+
+// Resolve replaces a "latest" or "release" Version with the concrete
+// version listed in the repository's maven-metadata.xml. Other versions
+// are left unchanged.
+func (this *Coordinate) Resolve(repository string) error {
+	if this.Version != Latest && this.Version != Release {
+		return nil
+	}
+	meta, err := GetMetadata(repository, this.Group, this.Artifact)
+	if err != nil {
+		return err
+	}
+	version := meta.Versioning.Release
+	if this.Version == Latest && meta.Versioning.Latest != "" {
+		version = meta.Versioning.Latest
+	}
+	if version == "" && len(meta.Versioning.Versions) > 0 {
+		version = meta.Versioning.Versions[len(meta.Versioning.Versions)-1]
+	}
+	if version == "" {
+		return fmt.Errorf("error: no %s version found for %s:%s",
+			this.Version, this.Group, this.Artifact)
+	}
+	this.Version = version
+	return nil
+}
+
+// Checksum downloads the checksum sidecar of the artifact from the given
+// repository. SHA256 is preferred over SHA1. The hash algorithm name is
+// returned along with the hex-encoded checksum.
+func (this *Coordinate) Checksum(repository string) (string, string, error) {
+	var err error
+	for _, algo := range []string{"sha256", "sha1"} {
+		var data []byte
+		if data, err = net.Get(this.Url(repository)+"."+algo, nil); err != nil {
+			continue
+		}
+		// Sidecars may contain the file name after the checksum
+		if fields := strings.Fields(string(data)); len(fields) > 0 {
+			return algo, strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", "", fmt.Errorf("error: no checksum found for %s: %v", this, err)
+}
+
+// dir returns the path of the artifact's directory in the repository.
+func (this *Coordinate) dir() string {
+	return strings.Replace(this.Group, ".", "/", -1) + "/" + this.Artifact
+}
+
+// Metadata values act as XML import containers for maven-metadata.xml.
+type Metadata struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Versioning struct {
+		Latest   string   `xml:"latest"`
+		Release  string   `xml:"release"`
+		Versions []string `xml:"versions>version"`
+	} `xml:"versioning"`
+}
+
+// GetMetadata downloads and parses the maven-metadata.xml of an artifact.
+func GetMetadata(repository, group, artifact string) (*Metadata, error) {
+	c := Coordinate{Group: group, Artifact: artifact}
+	data, err := net.Get(strings.TrimSuffix(repository, "/")+"/"+
+		c.dir()+"/maven-metadata.xml", nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta Metadata
+	if err := xml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("error: invalid maven metadata for %s:%s: %v",
+			group, artifact, err)
+	}
+	return &meta, nil
+}
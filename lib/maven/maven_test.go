@@ -0,0 +1,112 @@
+package maven
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serve starts a stand-in Maven repository serving each path's contents,
+// and 404 otherwise.
+func serve(t *testing.T, files map[string]string) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/"
+}
+
+func TestParse(t *testing.T) {
+	for _, el := range []struct {
+		Coordinate, Path, String string
+	}{
+		{"net.minecraftforge:forge:1.20.1-47.2.0:installer",
+			"net/minecraftforge/forge/1.20.1-47.2.0/forge-1.20.1-47.2.0-installer.jar",
+			"net.minecraftforge:forge:1.20.1-47.2.0:installer"},
+		{"org.ow2.asm:asm:9.5", "org/ow2/asm/asm/9.5/asm-9.5.jar", "org.ow2.asm:asm:9.5"},
+		{"de.oceanlabs.mcp:mcp_config:1.20.1@zip", "de/oceanlabs/mcp/mcp_config/1.20.1/mcp_config-1.20.1.zip",
+			"de.oceanlabs.mcp:mcp_config:1.20.1@zip"},
+	} {
+		c, err := Parse(el.Coordinate)
+		if err != nil {
+			t.Errorf("%s: %v", el.Coordinate, err)
+			continue
+		}
+		if c.Path() != el.Path {
+			t.Errorf("%s: got path %s, want %s", el.Coordinate, c.Path(), el.Path)
+		}
+		if c.String() != el.String {
+			t.Errorf("%s: got string %s", el.Coordinate, c.String())
+		}
+	}
+	for _, el := range []string{"forge", "net.minecraftforge:forge", "a:b:c:d:e", "a::c", "a:b:c:"} {
+		if _, err := Parse(el); err == nil {
+			t.Errorf("%s: invalid coordinate parsed", el)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	repo := serve(t, map[string]string{
+		"/com/example/lib/maven-metadata.xml": `<metadata><groupId>com.example</groupId><artifactId>lib</artifactId>
+			<versioning><latest>2.1-SNAPSHOT</latest><release>2.0</release>
+			<versions><version>1.0</version><version>2.0</version><version>2.1-SNAPSHOT</version></versions>
+			</versioning></metadata>`,
+		"/com/example/bare/maven-metadata.xml": `<metadata><versioning><versions>
+			<version>1.0</version><version>1.1</version></versions></versioning></metadata>`,
+		"/com/example/empty/maven-metadata.xml": `<metadata></metadata>`,
+	})
+	for _, el := range []struct {
+		Coordinate, Want string
+	}{
+		{"com.example:lib:latest", "2.1-SNAPSHOT"},
+		{"com.example:lib:release", "2.0"},
+		{"com.example:lib:1.0", "1.0"},
+		{"com.example:bare:release", "1.1"},
+	} {
+		c, _ := Parse(el.Coordinate)
+		if err := c.Resolve(repo); err != nil {
+			t.Errorf("%s: %v", el.Coordinate, err)
+		} else if c.Version != el.Want {
+			t.Errorf("%s: got version %s, want %s", el.Coordinate, c.Version, el.Want)
+		}
+	}
+	for _, el := range []string{"com.example:empty:latest", "com.example:missing:latest"} {
+		c, _ := Parse(el)
+		if err := c.Resolve(repo); err == nil {
+			t.Errorf("%s: unresolvable version resolved", el)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	repo := serve(t, map[string]string{
+		"/com/example/lib/1.0/lib-1.0.jar.sha1":   "DA39A3EE5E6B4B0D3255BFEF95601890AFD80709  lib-1.0.jar\n",
+		"/com/example/lib/2.0/lib-2.0.jar.sha256": "e3b0c44298fc1c149afbf4c8996fb924",
+		"/com/example/lib/2.0/lib-2.0.jar.sha1":   "da39a3ee",
+	})
+	for _, el := range []struct {
+		Coordinate, Algo, Sum string
+	}{
+		{"com.example:lib:1.0", "sha1", "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		{"com.example:lib:2.0", "sha256", "e3b0c44298fc1c149afbf4c8996fb924"},
+	} {
+		c, _ := Parse(el.Coordinate)
+		algo, sum, err := c.Checksum(repo)
+		if err != nil {
+			t.Errorf("%s: %v", el.Coordinate, err)
+		} else if algo != el.Algo || sum != el.Sum {
+			t.Errorf("%s: got checksum %s:%s, want %s:%s", el.Coordinate, algo, sum, el.Algo, el.Sum)
+		}
+	}
+	c, _ := Parse("com.example:lib:3.0")
+	if _, _, err := c.Checksum(repo); err == nil {
+		t.Error("missing checksum found")
+	}
+}
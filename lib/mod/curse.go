@@ -0,0 +1,221 @@
+package mod
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CurseApi is the base URL of the CurseForge REST API.
+var CurseApi = "https://api.curseforge.com"
+
+// CurseApiKey is the key used to authenticate with the CurseForge API.
+var CurseApiKey = ""
+
+// Legacy Curseforge download URLs have the following form, and may still
+// be given in place of a file ID:
+//
+//	https://minecraft.curseforge.com/projects/{mod_name}/files/{file_id}/download
+var curse_legacy_url = regexp.MustCompile(
+	"^https://minecraft\\.curseforge\\.com/projects/[^/]+/files/([0-9]+)/download$")
+
+// CurseForge hash algorithm identifiers.
+const curse_algo_sha1 = 1
+
+// CurseMod values represent mod files hosted on CurseForge. This adds
+// extra functionality inluding update checking. The "Curse" property
+// has the form "<mod_id>:<file_id>", where both are numeric CurseForge
+// IDs. A lone file ID or a legacy Curseforge download URL is also
+// accepted, in which case the mod ID is looked up.
+type CurseMod struct {
+	RemoteMod
+	// Curse is the CurseForge reference the mod was defined with.
+	Curse string
+	// ModId is the CurseForge project ID.
+	ModId int
+	// FileId is the CurseForge file ID.
+	FileId int
+	// Loader and Minecraft restrict the latest file to the given loader
+	// and Minecraft version, if set.
+	Loader    string
+	Minecraft string
+	// Size is the published size of the file in bytes.
+	Size int64
+}
+
+// curseFile values act as JSON import containers for CurseForge file
+// objects.
+type curseFile struct {
+	Id              int
+	ModId           int
+	FileName        string
+	FileLength      int64
+	DownloadUrl     string
+	FileFingerprint uint32
+	Hashes          []struct {
+		Value string
+		Algo  int
+	}
+}
+
+// Init parses the Curse property for the CurseMod and resolves the file
+// through the CurseForge API, setting the download URL accordingly. If no
+// reference checksum was given, the published SHA1 checksum is used, or
+// the murmur2 fingerprint if no SHA1 checksum is published.
+func (this *CurseMod) Init() error {
+	if err := this.parse(); err != nil {
+		return err
+	}
+	if CurseApiKey == "" {
+		return &InitError{Raw{Curse: this.Curse},
+			"mod init error: a CurseForge API key is required for 'curse' mods"}
+	}
+	file := curseFile{}
+	if this.ModId != 0 {
+		var resp struct{ Data curseFile }
+		err := net.GetJSON(fmt.Sprintf("%s/v1/mods/%d/files/%d",
+			CurseApi, this.ModId, this.FileId), curseHeader(), &resp)
+		if err != nil {
+			return err
+		}
+		file = resp.Data
+	} else {
+		// Look up the mod ID from the file ID
+		var resp struct{ Data []curseFile }
+		err := net.PostJSON(CurseApi+"/v1/mods/files", curseHeader(),
+			map[string][]int{"fileIds": {this.FileId}}, &resp)
+		if err != nil {
+			return err
+		} else if len(resp.Data) == 0 {
+			return &InitError{Raw{Curse: this.Curse},
+				"mod init error: CurseForge file not found"}
+		}
+		file = resp.Data[0]
+		this.ModId = file.ModId
+	}
+	this.apply(&file)
+	return nil
+}
+
+// GetLatest gets the latest file ID for the mod from CurseForge, for the
+// CurseMod's loader and Minecraft version if set.
+func (this *CurseMod) GetLatest() (string, error) {
+	if this.ModId == 0 {
+		if err := this.Init(); err != nil {
+			return "", err
+		}
+	}
+	query := url.Values{"pageSize": {"1"}}
+	if this.Minecraft != "" {
+		query.Set("gameVersion", this.Minecraft)
+	}
+	if this.Loader != "" {
+		query.Set("modLoaderType", strconv.Itoa(curse_loader_types[this.Loader]))
+	}
+	var resp struct{ Data []curseFile }
+	err := net.GetJSON(fmt.Sprintf("%s/v1/mods/%d/files?%s",
+		CurseApi, this.ModId, query.Encode()), curseHeader(), &resp)
+	if err != nil {
+		return "", err
+	} else if len(resp.Data) == 0 {
+		return "", fmt.Errorf("error: no files found for CurseForge mod %d", this.ModId)
+	}
+	return strconv.Itoa(resp.Data[0].Id), nil
+}
+
+// SetTarget sets the CurseMod value to track the given remote file from
+// CurseForge. A lone file ID is taken to belong to the current mod.
+func (this *CurseMod) SetTarget(curse string) error {
+	if this.ModId != 0 && !strings.Contains(curse, ":") {
+		curse = fmt.Sprintf("%d:%s", this.ModId, curse)
+	}
+	this.Curse = curse
+	// The reference checksum belongs to the previous file
+	this.checksum = ""
+	return this.Init()
+}
+
+// CurseSlugs returns the slugs of the given CurseForge mods, by mod ID.
+// Mods that are not found are left out.
+func CurseSlugs(ids []int) (map[int]string, error) {
+	var resp struct {
+		Data []struct {
+			Id   int
+			Slug string
+		}
+	}
+	err := net.PostJSON(CurseApi+"/v1/mods", curseHeader(), map[string][]int{"modIds": ids}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	slugs := map[int]string{}
+	for _, el := range resp.Data {
+		slugs[el.Id] = el.Slug
+	}
+	return slugs, nil
+}
+
+// parse sets the mod and file IDs from the Curse property.
+func (this *CurseMod) parse() error {
+	this.ModId, this.FileId = 0, 0
+	ref := this.Curse
+	if match := curse_legacy_url.FindStringSubmatch(ref); match != nil {
+		ref = match[1]
+	}
+	split := strings.SplitN(ref, ":", 2)
+	var err error
+	if len(split) == 2 {
+		if this.ModId, err = strconv.Atoi(split[0]); err == nil {
+			this.FileId, err = strconv.Atoi(split[1])
+		}
+	} else {
+		this.FileId, err = strconv.Atoi(split[0])
+	}
+	if err != nil || this.FileId <= 0 || this.ModId < 0 {
+		return &InitError{Raw{Curse: this.Curse},
+			"mod init error: 'curse' property is invalid"}
+	}
+	return nil
+}
+
+// apply sets the CurseMod's download properties from the given file. The
+// published file name is only used for the CDN URL: the Filename is
+// derived from the mod name and URL like that of other mods, so that it
+// changes with the file and never collides with another mod's jar.
+func (this *CurseMod) apply(file *curseFile) {
+	this.url, this.Size = file.DownloadUrl, file.FileLength
+	if this.url == "" {
+		// Third-party downloads are disabled for the file - use the CDN
+		this.url = fmt.Sprintf("https://edge.forgecdn.net/files/%d/%d/%s",
+			file.Id/1000, file.Id%1000, url.PathEscape(file.FileName))
+	}
+	if this.checksum != "" {
+		return
+	}
+	for _, el := range file.Hashes {
+		if el.Algo == curse_algo_sha1 {
+			this.checksum, this.hash = el.Value, sha1.New()
+			return
+		}
+	}
+	if file.FileFingerprint != 0 {
+		this.checksum = fmt.Sprintf("%08x", file.FileFingerprint)
+		this.hash = &net.Murmur2Hash{}
+	}
+}
+
+// FileSize returns the published size of the CurseMod's file, which the
+// download is checked against.
+func (this *CurseMod) FileSize() int64 {
+	return this.Size
+}
+
+// curseHeader returns the headers for CurseForge API requests.
+func curseHeader() http.Header {
+	return http.Header{"X-Api-Key": {CurseApiKey}}
+}
@@ -0,0 +1,150 @@
+package mod
+
+import (
+	"github.com/faceless-saint/m3/lib/net"
+	"path/filepath"
+	"testing"
+)
+
+// curseFileJSON is a CurseForge file of mod 238222 with a download URL and
+// a published SHA1 hash.
+const curseFileJSON = `{
+	"id": 4567890, "modId": 238222, "fileName": "jei-1.20.1.jar", "fileLength": 1234,
+	"downloadUrl": "https://edge.forgecdn.net/files/4567/890/jei-1.20.1.jar",
+	"fileFingerprint": 3735928559,
+	"hashes": [{"value": "0ab1c2", "algo": 1}, {"value": "d3e4f5", "algo": 2}]
+}`
+
+func TestCurseModFile(t *testing.T) {
+	received := serve(t, map[string]string{"GET /v1/mods/238222/files/4567890": `{"data": ` + curseFileJSON + `}`})
+	dl, err := New(&Raw{Name: "jei", Curse: "238222:4567890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received["POST /v1/mods/files"] != 0 {
+		t.Error("mod ID was looked up")
+	}
+	mod := dl.(*CurseMod)
+	if mod.ModId != 238222 || mod.FileId != 4567890 {
+		t.Errorf("got file %d:%d, want 238222:4567890", mod.ModId, mod.FileId)
+	}
+	if mod.Url() != "https://edge.forgecdn.net/files/4567/890/jei-1.20.1.jar" {
+		t.Errorf("got URL %s, want the download URL", mod.Url())
+	}
+	if mod.Checksum() != "0ab1c2" || mod.Hash().Size() != 20 {
+		t.Errorf("got checksum %s, want the SHA1 hash", mod.Checksum())
+	}
+	if mod.FileSize() != 1234 {
+		t.Errorf("got size %d, want 1234", mod.FileSize())
+	}
+}
+
+func TestCurseFileId(t *testing.T) {
+	received := serve(t, map[string]string{
+		"POST /v1/mods/files": `{"data": [` + curseFileJSON + `]}`,
+	})
+	for _, ref := range []string{"4567890", "https://minecraft.curseforge.com/projects/jei/files/4567890/download"} {
+		dl, err := New(&Raw{Name: "jei", Curse: ref})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mod := dl.(*CurseMod); mod.ModId != 238222 || mod.FileId != 4567890 {
+			t.Errorf("%s: got file %d:%d, want 238222:4567890", ref, mod.ModId, mod.FileId)
+		}
+	}
+	if received["POST /v1/mods/files"] != 2 {
+		t.Error("mod ID was not looked up")
+	}
+
+	serve(t, map[string]string{"POST /v1/mods/files": `{"data": []}`})
+	if _, err := New(&Raw{Name: "jei", Curse: "4567890"}); err == nil {
+		t.Error("missing file accepted")
+	}
+}
+
+func TestCurseFingerprint(t *testing.T) {
+	serve(t, map[string]string{"GET /v1/mods/238222/files/4567890": `{"data": {
+		"id": 4567890, "modId": 238222, "fileName": "jei-1.20.1.jar", "fileLength": 1234,
+		"downloadUrl": "https://edge.forgecdn.net/files/4567/890/jei-1.20.1.jar",
+		"fileFingerprint": 48879, "hashes": [{"value": "d3e4f5", "algo": 2}]
+	}}`})
+	dl, err := New(&Raw{Name: "jei", Curse: "238222:4567890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dl.Hash().(*net.Murmur2Hash); !ok || dl.Checksum() != "0000beef" {
+		t.Errorf("got checksum %s, want the murmur2 fingerprint 0000beef", dl.Checksum())
+	}
+}
+
+func TestCurseCDN(t *testing.T) {
+	serve(t, map[string]string{"GET /v1/mods/238222/files/4567890": `{"data": {
+		"id": 4567890, "modId": 238222, "fileName": "Just Enough Items.jar", "fileLength": 1234,
+		"downloadUrl": null, "hashes": [{"value": "0ab1c2", "algo": 1}]
+	}}`})
+	dl, err := New(&Raw{Name: "jei", Curse: "238222:4567890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Url() != "https://edge.forgecdn.net/files/4567/890/Just%20Enough%20Items.jar" {
+		t.Errorf("got URL %s, want the CDN URL", dl.Url())
+	}
+}
+
+func TestCurseSize(t *testing.T) {
+	serve(t, map[string]string{"GET /v1/mods/238222/files/4567890": `{"data": ` + curseFileJSON + `}`})
+	dl, err := New(&Raw{Name: "jei", Curse: "238222:4567890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := net.GetFileDeferred(dl, filepath.Join(t.TempDir(), dl.Filename()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Size != 1234 {
+		t.Errorf("got download size %d, want 1234", req.Size)
+	}
+}
+
+func TestCurseLatestFiltered(t *testing.T) {
+	received := serve(t, map[string]string{
+		"GET /v1/mods/238222/files/4567890": `{"data": ` + curseFileJSON + `}`,
+		"GET /v1/mods/238222/files":         `{"data": [{"id": 4567891}]}`,
+	})
+	dl, err := NewFor(&Raw{Name: "jei", Curse: "238222:4567890"}, "forge", "1.20.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := dl.(*CurseMod).GetLatest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != "4567891" {
+		t.Errorf("got latest file %s, want 4567891", latest)
+	}
+	if received["GET /v1/mods/238222/files?gameVersion=1.20.1&modLoaderType=1&pageSize=1"] != 1 {
+		t.Errorf("latest file not filtered by loader and Minecraft version: %v", received)
+	}
+}
+
+func TestCurseSetTarget(t *testing.T) {
+	serve(t, map[string]string{
+		"GET /v1/mods/238222/files/4567890": `{"data": ` + curseFileJSON + `}`,
+		"GET /v1/mods/238222/files/4567891": `{"data": {
+			"id": 4567891, "modId": 238222, "fileName": "jei-1.20.1-new.jar", "fileLength": 2345,
+			"downloadUrl": "https://edge.forgecdn.net/files/4567/891/jei-1.20.1-new.jar",
+			"hashes": [{"value": "6789ab", "algo": 1}]
+		}}`,
+	})
+	dl, err := New(&Raw{Name: "jei", Curse: "238222:4567890"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod := dl.(*CurseMod)
+	if err := mod.SetTarget("4567891"); err != nil {
+		t.Fatal(err)
+	}
+	if mod.FileId != 4567891 || mod.Checksum() != "6789ab" || mod.FileSize() != 2345 {
+		t.Errorf("got file %d with checksum %s, want those of file 4567891", mod.FileId, mod.Checksum())
+	}
+}
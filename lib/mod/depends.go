@@ -0,0 +1,124 @@
+package mod
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Dependency issue kinds.
+const (
+	// IssueMissing issues are required mods that are not installed.
+	IssueMissing = "missing"
+	// IssueVersion issues are mods installed in a version outside the
+	// required range.
+	IssueVersion = "version"
+	// IssueIncompatible issues are installed mods declared incompatible.
+	IssueIncompatible = "incompatible"
+	// IssueDiscouraged issues are installed mods that are discouraged, but
+	// still work.
+	IssueDiscouraged = "discouraged"
+)
+
+// Issue values describe a problem with a dependency of an installed mod.
+type Issue struct {
+	Kind string
+	// Mod is the mod declaring the dependency.
+	Mod Metadata
+	// Dependency is the declared dependency.
+	Dependency Dependency
+	// Found is the installed version of the other mod, if any.
+	Found string
+}
+
+func (this Issue) String() string {
+	mod := fmt.Sprintf("%s (%s)", this.Mod.Id, filepath.Base(this.Mod.File))
+	dep := &this.Dependency
+	switch this.Kind {
+	case IssueMissing:
+		return fmt.Sprintf("%s requires %s %s, which is missing", mod, dep.Id, dep.Range())
+	case IssueVersion:
+		return fmt.Sprintf("%s requires %s %s, but %s is installed", mod, dep.Id, dep.Range(), this.Found)
+	case IssueIncompatible:
+		return fmt.Sprintf("%s is incompatible with %s %s", mod, dep.Id, this.Found)
+	}
+	return fmt.Sprintf("%s is discouraged with %s %s", mod, dep.Id, this.Found)
+}
+
+// Fatal returns true iff the issue keeps the game from starting. Discouraged
+// mods and the recommended versions of optional Fabric dependencies are only
+// warned about.
+func (this Issue) Fatal() bool {
+	switch {
+	case this.Kind == IssueDiscouraged:
+		return false
+	case this.Kind == IssueVersion && this.Dependency.Kind == DependOptional:
+		return this.Dependency.Syntax != SyntaxSemver
+	}
+	return true
+}
+
+// CheckDependencies returns the issues with the dependencies of the given
+// mods. 'provided' holds the versions of the mod IDs provided by the
+// loader and game, such as "minecraft". Dependencies only applying to the
+// other side are skipped, unless the side is empty or SideBoth.
+func CheckDependencies(mods []Metadata, provided map[string]string, side string) []Issue {
+	installed := map[string]string{}
+	for id, version := range provided {
+		installed[strings.ToLower(id)] = version
+	}
+	for _, el := range mods {
+		for _, id := range append([]string{el.Id}, el.Provides...) {
+			if _, ok := installed[strings.ToLower(id)]; !ok || el.Id == id {
+				installed[strings.ToLower(id)] = el.Version
+			}
+		}
+	}
+	issues := []Issue{}
+	for _, el := range mods {
+		for _, dep := range el.Dependencies {
+			if strings.EqualFold(dep.Id, el.Id) {
+				continue
+			}
+			if side != "" && side != SideBoth && dep.Side != "" && dep.Side != SideBoth && dep.Side != side {
+				continue
+			}
+			version, ok := installed[strings.ToLower(dep.Id)]
+			issue := Issue{Mod: el, Dependency: dep, Found: version}
+			switch dep.Kind {
+			case DependRequired, DependOptional:
+				if !ok && dep.Kind == DependRequired {
+					issue.Kind = IssueMissing
+				} else if ok && !dep.Matches(version) {
+					issue.Kind = IssueVersion
+				}
+			case DependIncompatible, DependDiscouraged:
+				if ok && dep.Matches(version) {
+					issue.Kind = IssueIncompatible
+					if dep.Kind == DependDiscouraged {
+						issue.Kind = IssueDiscouraged
+					}
+				}
+			}
+			if issue.Kind != "" {
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return issues
+}
+
+// MissingDependencies returns the required dependencies of the issues that
+// are missing, once per mod ID.
+func MissingDependencies(issues []Issue) []Dependency {
+	deps, seen := []Dependency{}, map[string]*struct{}{}
+	for _, el := range issues {
+		id := strings.ToLower(el.Dependency.Id)
+		if _, ok := seen[id]; ok || el.Kind != IssueMissing {
+			continue
+		}
+		seen[id] = new(struct{})
+		deps = append(deps, el.Dependency)
+	}
+	return deps
+}
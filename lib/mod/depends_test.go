@@ -0,0 +1,113 @@
+package mod
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckDependencies(t *testing.T) {
+	provided := map[string]string{"minecraft": "1.20.1", "forge": "47.2.0"}
+	for _, test := range []struct {
+		Name string
+		Mods []Metadata
+		Side string
+		// Want lists the "<kind> <mod> <dependency>" of each issue
+		Want []string
+	}{
+		{"satisfied", []Metadata{
+			{Id: "create", Dependencies: []Dependency{
+				{"forge", DependRequired, []string{"[47,)"}, SyntaxMaven, SideBoth},
+				{"minecraft", DependRequired, []string{"[1.20.1,1.20.2)"}, SyntaxMaven, SideBoth},
+				{"flywheel", DependRequired, []string{"[0.6.10]"}, SyntaxMaven, SideBoth},
+			}},
+			{Id: "flywheel", Version: "0.6.10"},
+		}, "", []string{}},
+		{"missing", []Metadata{
+			{Id: "create", Dependencies: []Dependency{
+				{"flywheel", DependRequired, nil, SyntaxMaven, SideBoth},
+				{"jei", DependOptional, nil, SyntaxMaven, SideBoth},
+			}},
+		}, "", []string{"missing create flywheel"}},
+		{"version", []Metadata{
+			{Id: "create", Dependencies: []Dependency{
+				{"forge", DependRequired, []string{"[48,)"}, SyntaxMaven, SideBoth},
+				{"jei", DependOptional, []string{"[15,)"}, SyntaxMaven, SideBoth},
+			}},
+			{Id: "jei", Version: "14.0"},
+		}, "", []string{"version create forge", "version create jei"}},
+		{"incompatible", []Metadata{
+			{Id: "sodium", Dependencies: []Dependency{
+				{"optifabric", DependIncompatible, []string{"<1.13"}, SyntaxSemver, SideBoth},
+				{"iris", DependDiscouraged, nil, SyntaxSemver, SideBoth},
+				{"canvas", DependIncompatible, nil, SyntaxSemver, SideBoth},
+			}},
+			{Id: "optifabric", Version: "1.12.0"},
+			{Id: "iris", Version: "1.6.4"},
+		}, "", []string{"incompatible sodium optifabric", "discouraged sodium iris"}},
+		{"provided", []Metadata{
+			{Id: "modmenu", Dependencies: []Dependency{
+				{"menu_api", DependRequired, nil, SyntaxSemver, SideBoth},
+				{"MinecraftBase", DependRequired, nil, SyntaxSemver, SideBoth},
+			}},
+			{Id: "menu", Provides: []string{"menu_api"}},
+		}, "", []string{"missing modmenu MinecraftBase"}},
+		{"case insensitive", []Metadata{
+			{Id: "create", Dependencies: []Dependency{
+				{"Minecraft", DependRequired, nil, SyntaxMaven, SideBoth},
+				{"Create", DependRequired, nil, SyntaxMaven, SideBoth},
+			}},
+		}, "", []string{}},
+		{"other side", []Metadata{
+			{Id: "create", Dependencies: []Dependency{
+				{"jei", DependRequired, nil, SyntaxMaven, SideClient},
+				{"spark", DependRequired, nil, SyntaxMaven, SideServer},
+			}},
+		}, SideServer, []string{"missing create spark"}},
+		{"both sides", []Metadata{
+			{Id: "create", Dependencies: []Dependency{
+				{"jei", DependRequired, nil, SyntaxMaven, SideClient},
+				{"spark", DependRequired, nil, SyntaxMaven, SideServer},
+			}},
+		}, SideBoth, []string{"missing create jei", "missing create spark"}},
+	} {
+		got := []string{}
+		for _, el := range CheckDependencies(test.Mods, provided, test.Side) {
+			got = append(got, el.Kind+" "+el.Mod.Id+" "+el.Dependency.Id)
+		}
+		if !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("%s: got issues %q, want %q", test.Name, got, test.Want)
+		}
+	}
+}
+
+func TestIssueFatal(t *testing.T) {
+	for _, test := range []struct {
+		Issue Issue
+		Want  bool
+	}{
+		{Issue{Kind: IssueMissing, Dependency: Dependency{Kind: DependRequired}}, true},
+		{Issue{Kind: IssueIncompatible, Dependency: Dependency{Kind: DependIncompatible}}, true},
+		{Issue{Kind: IssueDiscouraged, Dependency: Dependency{Kind: DependDiscouraged}}, false},
+		{Issue{Kind: IssueVersion, Dependency: Dependency{Kind: DependRequired, Syntax: SyntaxSemver}}, true},
+		// Recommended Fabric versions are only warned about
+		{Issue{Kind: IssueVersion, Dependency: Dependency{Kind: DependOptional, Syntax: SyntaxSemver}}, false},
+		{Issue{Kind: IssueVersion, Dependency: Dependency{Kind: DependOptional, Syntax: SyntaxMaven}}, true},
+	} {
+		if got := test.Issue.Fatal(); got != test.Want {
+			t.Errorf("%s %s issue: Fatal() = %v, want %v", test.Issue.Dependency.Kind,
+				test.Issue.Kind, got, test.Want)
+		}
+	}
+}
+
+func TestMissingDependencies(t *testing.T) {
+	issues := CheckDependencies([]Metadata{
+		{Id: "create", Dependencies: []Dependency{{"flywheel", DependRequired, nil, SyntaxMaven, SideBoth}}},
+		{Id: "steam", Dependencies: []Dependency{{"Flywheel", DependRequired, nil, SyntaxMaven, SideBoth}}},
+		{Id: "sodium", Dependencies: []Dependency{{"create", DependIncompatible, nil, SyntaxSemver, SideBoth}}},
+	}, nil, "")
+	deps := MissingDependencies(issues)
+	if len(deps) != 1 || deps[0].Id != "flywheel" {
+		t.Errorf("got missing dependencies %+v, want flywheel once", deps)
+	}
+}
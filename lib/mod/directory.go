@@ -3,7 +3,9 @@ package mod
 import (
 	"github.com/cavaliercoder/grab"
 	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/plan"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,24 +17,70 @@ type Directory struct {
 	Ignore []string
 	// Items is a list of definitions for downloadable mod files.
 	Items net.Downloadables
+	// Features are the optional mods and named groups of mods.
+	Features []*Feature
+	// Selection is the choice of features applied by Select.
+	Selection Selection
+	// Inactive lists the mods removed by Filter or Select. Their jars are
+	// disabled rather than deleted.
+	Inactive net.Downloadables
 }
 
 // RawDirectory values act as JSON import containers for Directory values.
 type RawDirectory struct {
 	Ignore []string
 	Items  []Raw
+	// Groups are named groups of optional mods, by mod name.
+	Groups map[string]RawGroup
+	// Remove and Unignore name the mods and ignore entries of an extended
+	// spec to drop.
+	Remove   []string
+	Unignore []string
 }
 
 // NewDirectory returns a new Directory value from the imported RawDirectory.
+// Local mod paths are resolved relative to the current directory.
 func NewDirectory(raw *RawDirectory) (*Directory, error) {
-	this := Directory{raw.Ignore, nil}
+	return NewDirectoryAt(raw, ".")
+}
+
+// NewDirectoryAt returns a new Directory value from the imported
+// RawDirectory, resolving local mod paths relative to 'base'. If base is
+// a remote URL, local mods are downloaded from alongside it instead.
+func NewDirectoryAt(raw *RawDirectory, base string) (*Directory, error) {
+	return NewDirectoryWith(raw, base, New)
+}
+
+// NewDirectoryWith returns a new Directory value like NewDirectoryAt, but
+// initializes each mod with 'newMod' in place of New.
+func NewDirectoryWith(raw *RawDirectory, base string, newMod func(*Raw) (net.Downloadable, error)) (*Directory, error) {
+	remote, err := url.Parse(base)
+	if err != nil || (remote.Scheme != "http" && remote.Scheme != "https") {
+		remote = nil
+	}
+	this := Directory{Ignore: raw.Ignore}
 	for _, el := range raw.Items {
-		mod, err := New(&el)
+		if remote != nil && el.Path != "" {
+			ref, err := url.Parse(filepath.ToSlash(el.Path))
+			if err != nil {
+				return nil, &InitError{el, "mod init error: 'path' property is invalid"}
+			}
+			el.Url, el.Path = remote.ResolveReference(ref).String(), ""
+		}
+		mod, err := newMod(&el)
 		if err != nil {
 			return nil, err
 		}
+		if local, ok := mod.(*LocalMod); ok {
+			if err := local.SetBase(base); err != nil {
+				return nil, err
+			}
+		}
 		this.Items = append(this.Items, mod)
 	}
+	if this.Features, err = newFeatures(raw, this.Items); err != nil {
+		return nil, err
+	}
 	return &this, nil
 }
 
@@ -59,67 +107,127 @@ func (this *Directory) FetchTo(dir string, num int, verbose bool) (<-chan *grab.
 // If any disabled mods match file names that are now called for, they
 // will automatically be enabled again if they pass checksum validation.
 func (this *Directory) Clean(dir string) error {
-	if _, err := os.Stat(dir); err != nil {
-		// Directory does not exist - nothing to clean
-		return nil
-	}
-	files, err := ioutil.ReadDir(dir)
+	p, err := this.Plan(dir)
 	if err != nil {
 		return err
 	}
-	ignoreMap := make(map[string]*struct{}, len(this.Ignore))
-	for _, el := range this.Ignore {
-		ignoreMap[el] = new(struct{})
+	return p.Apply()
+}
+
+// Plan computes the changes that Clean and the subsequent downloads
+// would make to the given mod directory, without modifying it. Files
+// failing checksum validation are deleted and downloaded again.
+func (this *Directory) Plan(dir string) (plan.Plan, error) {
+	p := plan.Plan{}
+	valid := make(map[string]*struct{}, len(this.Items))
+	if _, err := os.Stat(dir); err == nil {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		present := make(map[string]*struct{}, len(files))
+		for _, el := range files {
+			present[el.Name()] = new(struct{})
+		}
+		ignoreMap := make(map[string]*struct{}, len(this.Ignore))
+		for _, el := range this.Ignore {
+			ignoreMap[el] = new(struct{})
+		}
+		fileMap := make(map[string]net.Downloadable, len(this.Items))
+		for _, el := range this.Items {
+			fileMap[el.Filename()] = el
+		}
+		for _, el := range files {
+			if _, ok := ignoreMap[el.Name()]; ok {
+				// Mod is being deliberately ignored
+				continue
+			} else if mod, ok := fileMap[el.Name()]; ok {
+				// Mod should exist - verify checksum
+				match, err := net.CheckFile(filepath.Join(dir, el.Name()),
+					mod.Checksum(), mod.Hash())
+				if err != nil {
+					return nil, err
+				}
+				if match {
+					valid[el.Name()] = new(struct{})
+				} else {
+					p.Add(plan.Delete, filepath.Join(dir, el.Name()))
+				}
+			} else if mod, ok := fileMap[strings.TrimSuffix(el.Name(), plan.DisabledExt)]; ok {
+				// Mod should exist but is disabled - enable if valid
+				if _, ok := present[mod.Filename()]; ok {
+					continue
+				}
+				match, err := net.CheckFile(filepath.Join(dir, el.Name()),
+					mod.Checksum(), mod.Hash())
+				if err != nil {
+					return nil, err
+				}
+				if match {
+					valid[mod.Filename()] = new(struct{})
+					p = append(p, plan.Action{Op: plan.Enable,
+						Path:   filepath.Join(dir, el.Name()),
+						Target: filepath.Join(dir, mod.Filename())})
+				}
+			} else if filepath.Ext(el.Name()) == ".jar" {
+				// Mod file should not exist - disable with extension change
+				p.Add(plan.Disable, filepath.Join(dir, el.Name()))
+			}
+		}
 	}
-	fileMap := make(map[string]net.Downloadable, len(this.Items))
+	// Download every mod that will not be present after cleaning
 	for _, el := range this.Items {
-		fileMap[el.Filename()] = el
+		if _, ok := valid[el.Filename()]; !ok {
+			p = append(p, plan.Action{Op: plan.Download,
+				Path: filepath.Join(dir, el.Filename()), Url: el.Url()})
+		}
+	}
+	return p, nil
+}
+
+// Prune deletes the disabled jar files in the given directory, except
+// those of Inactive mods.
+func (this *Directory) Prune(dir string) error {
+	p, err := this.PlanPrune(dir, plan.Plan{})
+	if err != nil {
+		return err
+	}
+	return p.Apply()
+}
+
+// PlanPrune returns the plan 'p' for the given directory extended to
+// prune it like Prune: jars the plan disables are deleted instead, as are
+// the disabled jar files it does not enable. Those of Inactive mods are
+// kept.
+func (this *Directory) PlanPrune(dir string, p plan.Plan) (plan.Plan, error) {
+	keep := make(map[string]*struct{}, len(this.Inactive))
+	for _, el := range this.Inactive {
+		keep[filepath.Join(dir, el.Filename())] = new(struct{})
+	}
+	pruned := plan.Plan{}
+	for _, el := range p {
+		if _, ok := keep[el.Path]; el.Op == plan.Disable && !ok {
+			el.Op = plan.Delete
+		} else if el.Op == plan.Enable {
+			keep[strings.TrimSuffix(el.Path, plan.DisabledExt)] = new(struct{})
+		}
+		pruned = append(pruned, el)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.jar"+plan.DisabledExt))
+	if err != nil {
+		return nil, err
 	}
 	for _, el := range files {
-		if _, ok := ignoreMap[el.Name()]; ok {
-			// Mod is being deliberately ignored
-			continue
-		} else if mod, ok := fileMap[el.Name()]; ok {
-			// Mod should exist - verify checksum
-			err = net.VerifyFile(filepath.Join(dir, mod.Filename()),
-				mod.Checksum(), mod.Hash())
-			if err != nil {
-				return err
-			}
-		} else if mod, ok := fileMap[strings.Replace(el.Name(), ".disabled", "", 1)]; ok {
-			// Mod should exist but is disabled - enable and verify
-			err := os.Link(filepath.Join(dir, el.Name()),
-				filepath.Join(dir, mod.Filename()))
-			if err != nil {
-				return err
-			}
-			// Verify the checksum of the disabled mod
-			err = net.VerifyFile(filepath.Join(dir, mod.Filename()),
-				mod.Checksum(), mod.Hash())
-			if err != nil {
-				return err
-			}
-			if _, err := os.Stat(filepath.Join(dir, mod.Filename())); err == nil {
-				err = os.Remove(filepath.Join(dir, el.Name()))
-				if err != nil {
-					return err
-				}
-			}
-		} else if filepath.Ext(el.Name()) == ".jar" {
-			// Mod file should not exist - disable with extension change
-			err := os.Rename(filepath.Join(dir, el.Name()),
-				filepath.Join(dir, el.Name()+".disabled"))
-			if err != nil {
-				return err
-			}
+		if _, ok := keep[strings.TrimSuffix(el, plan.DisabledExt)]; !ok {
+			pruned.Add(plan.Delete, el)
 		}
 	}
-	return nil
+	return pruned, nil
 }
 
 // PruneDir deletes all disabled jar files in the given directory.
 func PruneDir(dir string) error {
-	files, err := filepath.Glob(".jar.disabled")
+	files, err := filepath.Glob(filepath.Join(dir, "*.jar.disabled"))
 	if err != nil {
 		return err
 	}
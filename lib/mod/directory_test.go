@@ -0,0 +1,61 @@
+package mod
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/plan"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanPrune(t *testing.T) {
+	sum := sha256.Sum256([]byte("common"))
+	common, err := New(&Raw{Name: "common", Url: "https://example.com/common.jar",
+		Checksum: hex.EncodeToString(sum[:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	minimap, err := New(&Raw{Name: "minimap", Url: "https://example.com/minimap.jar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	this := Directory{Items: net.Downloadables{common}, Inactive: net.Downloadables{minimap}}
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		common.Filename() + plan.DisabledExt:  "common",
+		minimap.Filename() + plan.DisabledExt: "minimap",
+		"removed.jar":                         "removed",
+		"stale.jar" + plan.DisabledExt:        "stale",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := this.Plan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err = this.PlanPrune(dir, p); err != nil {
+		t.Fatal(err)
+	}
+	ops := map[string]plan.Op{}
+	for _, el := range p {
+		ops[filepath.Base(el.Path)] = el.Op
+	}
+	want := map[string]plan.Op{
+		common.Filename() + plan.DisabledExt: plan.Enable,
+		"removed.jar":                        plan.Delete,
+		"stale.jar" + plan.DisabledExt:       plan.Delete,
+	}
+	if len(ops) != len(want) {
+		t.Errorf("got actions %v, want %v", ops, want)
+	}
+	for name, op := range want {
+		if ops[name] != op {
+			t.Errorf("got %q for %s, want %q", ops[name], name, op)
+		}
+	}
+}
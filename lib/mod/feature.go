@@ -0,0 +1,140 @@
+package mod
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// Feature values represent optional mods, or named groups of mods, that
+// are only installed if selected.
+type Feature struct {
+	Name string
+	// Default indicates that the feature is selected unless deselected.
+	Default bool
+	// Mods are the names of the mods provided by the feature.
+	Mods  []string
+	Items net.Downloadables
+}
+
+// RawGroup values act as JSON import containers for named groups. A
+// group is either an object or just the list of its mod names.
+type RawGroup struct {
+	Default bool
+	Mods    []string
+}
+
+func (this *RawGroup) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &this.Mods); err == nil {
+		return nil
+	}
+	type plain RawGroup
+	return json.Unmarshal(data, (*plain)(this))
+}
+
+// Selection values record the local choice of features, by name. Features
+// missing from the Selection use their default.
+type Selection map[string]bool
+
+// ReadSelection reads a Selection from the given JSON file. A missing file
+// is an empty Selection.
+func ReadSelection(file string) (Selection, error) {
+	this := Selection{}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return this, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &this); err != nil {
+		return nil, fmt.Errorf("error: invalid selection file %s: %v", file, err)
+	}
+	return this, nil
+}
+
+// WriteFile writes the Selection to the given file as JSON.
+func (this Selection) WriteFile(file string) error {
+	data, err := json.MarshalIndent(this, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// Selected returns true iff the feature is selected.
+func (this Selection) Selected(feature *Feature) bool {
+	if selected, ok := this[feature.Name]; ok {
+		return selected
+	}
+	return feature.Default
+}
+
+// Feature returns the feature of the Directory with the given name, or nil.
+func (this *Directory) Feature(name string) *Feature {
+	for _, el := range this.Features {
+		if el.Name == name {
+			return el
+		}
+	}
+	return nil
+}
+
+// Select removes the optional Items that are not provided by any feature
+// selected in 'sel'. The jars of removed mods are disabled by Clean.
+func (this *Directory) Select(sel Selection) {
+	this.Selection = sel
+	optional := map[net.Downloadable]bool{}
+	for _, feature := range this.Features {
+		for _, el := range feature.Items {
+			optional[el] = optional[el] || sel.Selected(feature)
+		}
+	}
+	items := net.Downloadables{}
+	for _, el := range this.Items {
+		if selected, ok := optional[el]; ok && !selected {
+			this.Inactive = append(this.Inactive, el)
+		} else {
+			items = append(items, el)
+		}
+	}
+	this.Items = items
+}
+
+// newFeatures returns the features defined by the optional mods and named
+// groups of the RawDirectory. 'items' holds the mod of each raw item.
+func newFeatures(raw *RawDirectory, items net.Downloadables) ([]*Feature, error) {
+	features, byName := []*Feature{}, map[string]net.Downloadable{}
+	for i, el := range raw.Items {
+		byName[el.Name] = items[i]
+		if el.Optional {
+			features = append(features, &Feature{el.Name, el.Default,
+				[]string{el.Name}, net.Downloadables{items[i]}})
+		}
+	}
+	names := []string{}
+	for name := range raw.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		group := raw.Groups[name]
+		feature := Feature{name, group.Default, group.Mods, nil}
+		for _, el := range group.Mods {
+			mod, ok := byName[el]
+			if !ok {
+				return nil, fmt.Errorf("error: group %q references unknown mod %q", name, el)
+			}
+			feature.Items = append(feature.Items, mod)
+		}
+		for _, el := range features {
+			if el.Name == name {
+				return nil, fmt.Errorf("error: group %q has the name of an optional mod", name)
+			}
+		}
+		features = append(features, &feature)
+	}
+	return features, nil
+}
@@ -0,0 +1,147 @@
+package mod
+
+import (
+	"fmt"
+	"github.com/faceless-saint/m3/lib/git"
+	"github.com/faceless-saint/m3/lib/net"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// GitHubReleaseMod values represent mod files attached to GitHub releases.
+// The "GitHub" property names the repository as "<owner>/<repo>", the
+// "Tag" property selects the release (default "latest"), and the "Asset"
+// property is a glob pattern matching the asset name. If no pattern is
+// given, the release must contain exactly one jar file.
+type GitHubReleaseMod struct {
+	RemoteMod
+	Repository git.Repository
+	// Tag is the release tag, or "latest".
+	Tag string
+	// Asset is the asset name pattern.
+	Asset string
+	// RemoteFilename is the name of the resolved asset.
+	RemoteFilename string
+	// Size is the published size of the asset in bytes.
+	Size int64
+	// apiUrl is the API download URL of the asset, used for authorized
+	// downloads from private repositories.
+	apiUrl string
+}
+
+// NewGitHubReleaseMod returns a new GitHubReleaseMod for the given
+// repository, release tag and asset pattern, based on the given RemoteMod.
+func NewGitHubReleaseMod(base RemoteMod, repository, tag, asset string) (*GitHubReleaseMod, error) {
+	repo, err := git.NewRepository(repository)
+	if err != nil {
+		return nil, &InitError{Raw{Name: base.Name, GitHub: repository},
+			"mod init error: 'github' property is invalid"}
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	if _, err := path.Match(asset, ""); err != nil {
+		return nil, &InitError{Raw{Name: base.Name, Asset: asset},
+			"mod init error: 'asset' property is invalid"}
+	}
+	this := GitHubReleaseMod{RemoteMod: base, Repository: *repo, Tag: tag, Asset: asset}
+	return &this, this.Init()
+}
+
+// Init resolves the release asset through the GitHub Releases API and
+// sets the download URL accordingly. The browser download URL is used to
+// derive the Filename, so it remains stable whether or not a GitHub token
+// is in use. If no reference checksum was given, the published asset
+// digest is used when available.
+func (this *GitHubReleaseMod) Init() error {
+	release, err := this.Repository.Release(this.Tag)
+	if err != nil {
+		return err
+	}
+	asset, err := this.match(release)
+	if err != nil {
+		return err
+	}
+	this.url, this.apiUrl = asset.Browser_download_url, asset.Url
+	this.RemoteFilename, this.Size = asset.Name, asset.Size
+	if this.Version == "" {
+		this.Version = release.Tag_name
+	}
+	if split := strings.SplitN(asset.Digest, ":", 2); this.checksum == "" && len(split) == 2 {
+		if h, err := net.NewHash(split[0]); err == nil {
+			this.checksum, this.hash = split[1], h
+		}
+	}
+	return nil
+}
+
+// Url returns the API download URL if a GitHub token is set, or the
+// public browser download URL otherwise.
+func (this *GitHubReleaseMod) Url() string {
+	if git.Token != "" && this.apiUrl != "" {
+		return this.apiUrl
+	}
+	return this.url
+}
+
+// PublicUrl returns the public browser download URL, regardless of the
+// GitHub token.
+func (this *GitHubReleaseMod) PublicUrl() string { return this.url }
+
+// ApiUrl returns the API download URL, used if a GitHub token is set.
+func (this *GitHubReleaseMod) ApiUrl() string { return this.apiUrl }
+
+// Header returns the extra headers required for authorized downloads.
+func (this *GitHubReleaseMod) Header() http.Header {
+	return GitHubHeader(this.apiUrl)
+}
+
+// GitHubHeader returns the extra headers required to download a release
+// asset with the given API download URL. Downloads are only authorized if
+// a GitHub token is set.
+func GitHubHeader(apiUrl string) http.Header {
+	header := git.Header()
+	if git.Token != "" && apiUrl != "" {
+		header.Set("Accept", "application/octet-stream")
+	}
+	return header
+}
+
+// GetLatest gets the tag of the latest release from GitHub.
+func (this *GitHubReleaseMod) GetLatest() (string, error) {
+	release, err := this.Repository.Release("latest")
+	if err != nil {
+		return "", err
+	}
+	return release.Tag_name, nil
+}
+
+// SetTarget sets the GitHubReleaseMod value to track the given release.
+func (this *GitHubReleaseMod) SetTarget(tag string) error {
+	this.Tag = tag
+	this.Version, this.checksum = "", ""
+	return this.Init()
+}
+
+// match returns the single asset of the release matching the pattern.
+func (this *GitHubReleaseMod) match(release *git.Release) (*git.Asset, error) {
+	matches := []*git.Asset{}
+	for i, el := range release.Assets {
+		var ok bool
+		if this.Asset != "" {
+			ok, _ = path.Match(this.Asset, el.Name)
+		} else {
+			ok = path.Ext(el.Name) == ".jar"
+		}
+		if ok {
+			matches = append(matches, &release.Assets[i])
+		}
+	}
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("error: %d assets of %s/%s release %s match %q - need exactly one",
+			len(matches), this.Repository.Owner, this.Repository.Name,
+			release.Tag_name, this.Asset)
+	}
+	return matches[0], nil
+}
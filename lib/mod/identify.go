@@ -0,0 +1,136 @@
+package mod
+
+import (
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"net/url"
+	"strconv"
+)
+
+// Identify looks up the given jars by their hashes and returns the Raw mod
+// of each identified jar, or nil for unidentified jars. Jars are looked up
+// on Modrinth by SHA512 hash first, then by CurseForge fingerprint if an
+// API key is set. The Raw mods have no name, and use the checksum of the
+// local jar.
+func Identify(files []string) ([]*Raw, error) {
+	raws := make([]*Raw, len(files))
+	sha512s, sha1s, fingerprints := []string{}, []string{}, []uint32{}
+	for _, el := range files {
+		sums, err := net.FileChecksums(el, sha512.New(), sha1.New(), &net.Murmur2Hash{})
+		if err != nil {
+			return nil, err
+		}
+		fingerprint, _ := strconv.ParseUint(sums[2], 16, 32)
+		sha512s, sha1s = append(sha512s, sums[0]), append(sha1s, sums[1])
+		fingerprints = append(fingerprints, uint32(fingerprint))
+	}
+	if len(files) == 0 {
+		return raws, nil
+	}
+
+	// Look up all jars on Modrinth
+	found, err := identifyModrinth(sha512s)
+	if err != nil {
+		return nil, err
+	}
+	remaining := []uint32{}
+	for i := range files {
+		if version, ok := found[sha512s[i]]; ok {
+			raws[i] = version.raw(sha512s[i])
+		} else {
+			remaining = append(remaining, fingerprints[i])
+		}
+	}
+	if len(remaining) == 0 || CurseApiKey == "" {
+		return raws, nil
+	}
+
+	// Look up the remaining jars on CurseForge
+	var resp struct {
+		Data struct {
+			ExactMatches []struct {
+				Id   int
+				File curseFile
+			}
+		}
+	}
+	err = net.PostJSON(fmt.Sprintf("%s/v1/fingerprints/%d", CurseApi, curse_game_minecraft),
+		curseHeader(), map[string][]uint32{"fingerprints": remaining}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range resp.Data.ExactMatches {
+		for i := range files {
+			if raws[i] == nil && fingerprints[i] == match.File.FileFingerprint {
+				raws[i] = &Raw{Curse: fmt.Sprintf("%d:%d", match.Id, match.File.Id),
+					Checksum: "sha1:" + sha1s[i]}
+			}
+		}
+	}
+	return raws, nil
+}
+
+// IdentifyModrinth looks up files on Modrinth by their SHA512 hashes, and
+// returns the Raw mod of each identified file by hash. The Raw mods are
+// named by the slug of their project, and use the given hashes as their
+// checksums.
+func IdentifyModrinth(hashes []string) (map[string]*Raw, error) {
+	raws := map[string]*Raw{}
+	if len(hashes) == 0 {
+		return raws, nil
+	}
+	found, err := identifyModrinth(hashes)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, el := range found {
+		ids = append(ids, el.Project_id)
+	}
+	var projects []struct{ Id, Slug string }
+	if len(ids) > 0 {
+		list, _ := json.Marshal(ids)
+		err := net.GetJSON(ModrinthApi+"/projects?ids="+url.QueryEscape(string(list)), nil, &projects)
+		if err != nil {
+			return nil, err
+		}
+	}
+	slugs := map[string]string{}
+	for _, el := range projects {
+		slugs[el.Id] = el.Slug
+	}
+	for hash, version := range found {
+		if raw := version.raw(hash); raw != nil {
+			raw.Name, raws[hash] = slugs[version.Project_id], raw
+		}
+	}
+	return raws, nil
+}
+
+// identifyModrinth returns the Modrinth versions of the files with the
+// given SHA512 hashes, by hash.
+func identifyModrinth(hashes []string) (map[string]modrinthVersion, error) {
+	found := map[string]modrinthVersion{}
+	err := net.PostJSON(ModrinthApi+"/version_files", nil,
+		map[string]interface{}{"hashes": hashes, "algorithm": "sha512"}, &found)
+	return found, err
+}
+
+// raw returns the Raw mod of the version's file with the given SHA512
+// hash. ModrinthMod values download the primary file of a version, so
+// other files of the version are referenced by URL.
+func (this *modrinthVersion) raw(hash string) *Raw {
+	primary, err := this.primary()
+	if err == nil && primary.Hashes["sha512"] == hash {
+		return &Raw{Modrinth: this.Project_id + ":" + this.Id, Checksum: "sha512:" + hash}
+	}
+	for _, el := range this.Files {
+		if el.Hashes["sha512"] == hash {
+			return &Raw{Url: el.Url, Version: this.Version_number, Checksum: "sha512:" + hash}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package mod
+
+import (
+	"crypto/sha1"
+	"crypto/sha512"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// jar writes a jar with the given contents into the directory, returning
+// its path and its SHA512, SHA1 and murmur2 checksums.
+func jar(t *testing.T, dir, name, data string) (string, []string) {
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := net.FileChecksums(file, sha512.New(), sha1.New(), &net.Murmur2Hash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file, sums
+}
+
+func TestIdentify(t *testing.T) {
+	dir := t.TempDir()
+	primary, primarySums := jar(t, dir, "sodium.jar", "primary file")
+	secondary, secondarySums := jar(t, dir, "sodium-extra.jar", "secondary file")
+	curse, curseSums := jar(t, dir, "jei.jar", "curse file")
+	unknown, _ := jar(t, dir, "unknown.jar", "unknown file")
+	fingerprint, _ := strconv.ParseUint(curseSums[2], 16, 32)
+
+	received := serve(t, map[string]string{
+		"POST /version_files": fmt.Sprintf(`{
+			"%[1]s": {"id": "VERSION1", "project_id": "AABBCCDD", "version_number": "1.2.3", "files": [
+				{"hashes": {"sha512": "%[2]s"}, "url": "https://cdn.modrinth.com/extra.jar", "primary": false},
+				{"hashes": {"sha512": "%[1]s"}, "url": "https://cdn.modrinth.com/sodium.jar", "primary": true}
+			]},
+			"%[2]s": {"id": "VERSION1", "project_id": "AABBCCDD", "version_number": "1.2.3", "files": [
+				{"hashes": {"sha512": "%[2]s"}, "url": "https://cdn.modrinth.com/extra.jar", "primary": false},
+				{"hashes": {"sha512": "%[1]s"}, "url": "https://cdn.modrinth.com/sodium.jar", "primary": true}
+			]}
+		}`, primarySums[0], secondarySums[0]),
+		"POST /v1/fingerprints/432": fmt.Sprintf(`{"data": {"exactMatches": [
+			{"id": 238222, "file": {"id": 4567890, "modId": 238222, "fileFingerprint": %d}}
+		]}}`, fingerprint),
+	})
+	raws, err := Identify([]string{primary, secondary, curse, unknown})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received["POST /version_files"] != 1 || received["POST /v1/fingerprints/432"] != 1 {
+		t.Errorf("got requests %v, want one lookup on each site", received)
+	}
+	want := []*Raw{
+		{Modrinth: "AABBCCDD:VERSION1", Checksum: "sha512:" + primarySums[0]},
+		{Url: "https://cdn.modrinth.com/extra.jar", Version: "1.2.3", Checksum: "sha512:" + secondarySums[0]},
+		{Curse: "238222:4567890", Checksum: "sha1:" + curseSums[1]},
+		nil,
+	}
+	for i, el := range raws {
+		if (el == nil) != (want[i] == nil) || el != nil && (el.Modrinth != want[i].Modrinth ||
+			el.Url != want[i].Url || el.Curse != want[i].Curse || el.Version != want[i].Version ||
+			el.Checksum != want[i].Checksum) {
+			t.Errorf("jar %d: got %+v, want %+v", i, el, want[i])
+		}
+	}
+}
+
+func TestIdentifyWithoutCurseKey(t *testing.T) {
+	dir := t.TempDir()
+	file, _ := jar(t, dir, "jei.jar", "curse file")
+	received := serve(t, map[string]string{"POST /version_files": `{}`})
+	CurseApiKey = ""
+	raws, err := Identify([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raws[0] != nil {
+		t.Errorf("got %+v, want an unidentified jar", raws[0])
+	}
+	if received["POST /v1/fingerprints/432"] != 0 {
+		t.Error("jar was looked up on CurseForge without an API key")
+	}
+}
@@ -0,0 +1,46 @@
+package mod
+
+import (
+	"path/filepath"
+)
+
+// LocalMod values represent mod files shipped alongside the spec, such as
+// custom or patched jars. The "Path" property is the location of the file
+// relative to the spec's directory. Local mods are copied (or hardlinked)
+// into the mod directory instead of being downloaded, and are verified
+// against their reference checksum in the same way.
+type LocalMod struct {
+	RemoteMod
+	// Path is the location of the file as given in the spec.
+	Path string
+	// source is the resolved location of the file.
+	source string
+}
+
+// NewLocalMod returns a new LocalMod for the given path, based on the
+// given RemoteMod. The path is taken relative to the current directory
+// until SetBase is called.
+func NewLocalMod(base RemoteMod, path string) (*LocalMod, error) {
+	// The URL is derived from the path as given, so that the Filename
+	// does not depend on where the spec is located.
+	base.url = "file:" + filepath.ToSlash(path)
+	this := LocalMod{RemoteMod: base, Path: path}
+	return &this, this.SetBase(".")
+}
+
+// SetBase resolves the LocalMod's path relative to the given directory.
+func (this *LocalMod) SetBase(dir string) error {
+	source := filepath.FromSlash(this.Path)
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(dir, source)
+	}
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return err
+	}
+	this.source = abs
+	return nil
+}
+
+// Source returns the resolved location of the file.
+func (this *LocalMod) Source() string { return this.source }
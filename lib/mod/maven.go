@@ -0,0 +1,76 @@
+package mod
+
+import (
+	"github.com/faceless-saint/m3/lib/maven"
+	"github.com/faceless-saint/m3/lib/net"
+)
+
+// MavenMod values represent mod files published to a Maven repository.
+// The "Maven" property is a coordinate of the form
+// "group:artifact:version[:classifier]", where the version may also be
+// "latest" or "release". The "Repository" property defaults to Maven
+// Central.
+type MavenMod struct {
+	RemoteMod
+	// Coordinate identifies the artifact in the repository.
+	Coordinate maven.Coordinate
+	// Repository is the URL of the Maven repository.
+	Repository string
+}
+
+// NewMavenMod returns a new MavenMod for the given coordinate and
+// repository, based on the given RemoteMod.
+func NewMavenMod(base RemoteMod, coordinate, repository string) (*MavenMod, error) {
+	c, err := maven.Parse(coordinate)
+	if err != nil {
+		return nil, &InitError{Raw{Name: base.Name, Maven: coordinate},
+			"mod init error: 'maven' property is invalid"}
+	}
+	if repository == "" {
+		repository = maven.Central
+	}
+	this := MavenMod{base, *c, repository}
+	return &this, this.Init()
+}
+
+// Init resolves the MavenMod's version and sets the download URL. If no
+// reference checksum was given, the checksum is read from the artifact's
+// sidecar file in the repository.
+func (this *MavenMod) Init() error {
+	if err := this.Coordinate.Resolve(this.Repository); err != nil {
+		return err
+	}
+	this.url = this.Coordinate.Url(this.Repository)
+	if this.Version == "" {
+		this.Version = this.Coordinate.Version
+	}
+	if this.checksum == "" {
+		algo, sum, err := this.Coordinate.Checksum(this.Repository)
+		if err != nil {
+			return err
+		}
+		h, err := net.NewHash(algo)
+		if err != nil {
+			return err
+		}
+		this.checksum, this.hash = sum, h
+	}
+	return nil
+}
+
+// GetLatest gets the latest release version of the mod from the
+// repository metadata.
+func (this *MavenMod) GetLatest() (string, error) {
+	c := this.Coordinate
+	c.Version = maven.Release
+	err := c.Resolve(this.Repository)
+	return c.Version, err
+}
+
+// SetTarget sets the MavenMod value to track the given artifact version.
+// The checksum is read again from the new version's sidecar file.
+func (this *MavenMod) SetTarget(version string) error {
+	this.Coordinate.Version = version
+	this.Version, this.checksum = "", ""
+	return this.Init()
+}
@@ -0,0 +1,486 @@
+package mod
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Dependency kinds.
+const (
+	DependRequired     = "required"
+	DependOptional     = "optional"
+	DependIncompatible = "incompatible"
+	// DependDiscouraged mods work together, but with known problems.
+	DependDiscouraged = "discouraged"
+)
+
+// Metadata values describe a mod as declared by the metadata files of its
+// jar: mcmod.info (legacy Forge), META-INF/mods.toml (Forge),
+// META-INF/neoforge.mods.toml (NeoForge) or fabric.mod.json (Fabric).
+type Metadata struct {
+	// Id is the mod ID.
+	Id string
+	// Name is the display name.
+	Name    string
+	Version string
+	// Loader is the loader the metadata is written for: "forge",
+	// "neoforge" or "fabric".
+	Loader string
+	// Side is the side the mod declares it runs on: SideClient, SideServer
+	// or SideBoth. Empty if the metadata does not declare it.
+	Side string
+	// Minecraft and LoaderVersion are the ranges of Minecraft and loader
+	// versions the mod declares it supports, if any.
+	Minecraft     string
+	LoaderVersion string
+	Dependencies  []Dependency
+	// Provides lists further mod IDs provided by the mod.
+	Provides []string
+	// File is the path of the jar. Mods found in jars nested in another
+	// jar have the path of the outer jar.
+	File string
+}
+
+// Dependency values describe a relationship of a mod with another mod.
+type Dependency struct {
+	// Id is the mod ID of the other mod.
+	Id string
+	// Kind is one of DependRequired, DependOptional, DependIncompatible or
+	// DependDiscouraged.
+	Kind string
+	// Versions are the version ranges the dependency applies to, any of
+	// which may match. Empty if it applies to every version.
+	Versions []string
+	// Syntax is the syntax of the version ranges: SyntaxMaven or
+	// SyntaxSemver.
+	Syntax string
+	// Side is the side the dependency applies to: SideClient, SideServer
+	// or SideBoth.
+	Side string
+}
+
+// Matches returns true iff the dependency applies to the given version of
+// the other mod.
+func (this *Dependency) Matches(version string) bool {
+	if len(this.Versions) == 0 || version == "" {
+		return true
+	}
+	for _, el := range this.Versions {
+		if MatchRange(version, el, this.Syntax) {
+			return true
+		}
+	}
+	return false
+}
+
+// Range returns the version ranges of the dependency for display.
+func (this *Dependency) Range() string {
+	if len(this.Versions) == 0 {
+		return "*"
+	}
+	return strings.Join(this.Versions, " || ")
+}
+
+// ReadMetadata returns the mods declared by the metadata files of the given
+// jar, including the mods of the jars nested in it. Jars without metadata,
+// such as plain libraries, declare no mods.
+func ReadMetadata(file string) ([]Metadata, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("error: failed to read jar %s: %v", file, err)
+	}
+	defer r.Close()
+	mods, err := readJar(&r.Reader, file)
+	if err != nil {
+		return nil, fmt.Errorf("error: failed to read metadata of %s: %v", file, err)
+	}
+	return mods, nil
+}
+
+// ReadMetadataFiles returns the mods declared by the given jars, in order.
+func ReadMetadataFiles(files []string) ([]Metadata, error) {
+	mods := []Metadata{}
+	for _, el := range files {
+		list, err := ReadMetadata(el)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, list...)
+	}
+	return mods, nil
+}
+
+// ReadMetadataDir returns the mods declared by every jar in the given mod
+// directory, which are the mods loaded by the game.
+func ReadMetadataDir(dir string) ([]Metadata, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jar"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return ReadMetadataFiles(files)
+}
+
+// readJar returns the mods declared in the opened jar and the jars nested
+// in it.
+func readJar(r *zip.Reader, file string) ([]Metadata, error) {
+	entries := map[string]*zip.File{}
+	for _, el := range r.File {
+		entries[el.Name] = el
+	}
+	mods := []Metadata{}
+	if f, ok := entries["fabric.mod.json"]; ok {
+		data, err := readEntry(f)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := parseFabric(data)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, *meta)
+	}
+	for _, el := range []struct{ Name, Loader string }{
+		{"META-INF/neoforge.mods.toml", "neoforge"}, {"META-INF/mods.toml", "forge"},
+	} {
+		f, ok := entries[el.Name]
+		if !ok {
+			continue
+		}
+		data, err := readEntry(f)
+		if err != nil {
+			return nil, err
+		}
+		list, err := parseModsToml(data, jarVersion(entries), el.Loader)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, list...)
+	}
+	if f, ok := entries["mcmod.info"]; ok && len(mods) == 0 {
+		data, err := readEntry(f)
+		if err != nil {
+			return nil, err
+		}
+		// Many legacy mods ship placeholder or malformed mcmod.info files
+		if list, err := parseMcmodInfo(data); err == nil {
+			mods = append(mods, list...)
+		}
+	}
+
+	// Nested jars are loaded along with the jar
+	for _, el := range r.File {
+		dir := filepath.ToSlash(filepath.Dir(el.Name))
+		if filepath.Ext(el.Name) != ".jar" || (dir != "META-INF/jars" && dir != "META-INF/jarjar") {
+			continue
+		}
+		data, err := readEntry(el)
+		if err != nil {
+			return nil, err
+		}
+		nested, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("nested jar %s: %v", el.Name, err)
+		}
+		list, err := readJar(nested, file)
+		if err != nil {
+			return nil, fmt.Errorf("nested jar %s: %v", el.Name, err)
+		}
+		mods = append(mods, list...)
+	}
+	for i := range mods {
+		mods[i].File = file
+		mods[i].constraints()
+	}
+	return mods, nil
+}
+
+// constraints sets the Minecraft and loader version ranges of the mod from
+// its required dependencies, unless declared otherwise.
+func (this *Metadata) constraints() {
+	for _, el := range this.Dependencies {
+		if el.Kind != DependRequired {
+			continue
+		}
+		switch strings.ToLower(el.Id) {
+		case "minecraft":
+			if this.Minecraft == "" {
+				this.Minecraft = el.Range()
+			}
+		case "forge", "neoforge", "fabricloader":
+			if this.LoaderVersion == "" {
+				this.LoaderVersion = el.Range()
+			}
+		}
+	}
+}
+
+// ForLoader returns the mods written for the given loader. Jars declaring
+// mods for several loaders are narrowed to the mods of the given loader,
+// and NeoForge also reads the Forge metadata of jars without NeoForge
+// metadata. Jars for other loaders only are kept as they are.
+func ForLoader(mods []Metadata, loader string) []Metadata {
+	loaders := map[string]map[string]bool{}
+	for _, el := range mods {
+		if loaders[el.File] == nil {
+			loaders[el.File] = map[string]bool{}
+		}
+		loaders[el.File][el.Loader] = true
+	}
+	list := []Metadata{}
+	for _, el := range mods {
+		want := loader
+		if loader == "neoforge" && !loaders[el.File]["neoforge"] {
+			want = "forge"
+		}
+		if el.Loader == want || !loaders[el.File][want] {
+			list = append(list, el)
+		}
+	}
+	return list
+}
+
+// Inspect returns the mods declared by the given jar, or by every jar in
+// the given directory. Jars without metadata are listed with just their
+// File set.
+func Inspect(path string) ([]Metadata, error) {
+	files := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.jar")); err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+	mods := []Metadata{}
+	for _, el := range files {
+		list, err := ReadMetadata(el)
+		if err != nil {
+			return nil, err
+		} else if len(list) == 0 {
+			list = []Metadata{{File: el}}
+		}
+		mods = append(mods, list...)
+	}
+	return mods, nil
+}
+
+// readEntry returns the contents of the jar entry.
+func readEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// jarVersion returns the Implementation-Version of the jar's manifest,
+// which Forge substitutes for "${file.jarVersion}".
+func jarVersion(entries map[string]*zip.File) string {
+	f, ok := entries["META-INF/MANIFEST.MF"]
+	if !ok {
+		return ""
+	}
+	data, err := readEntry(f)
+	if err != nil {
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		split := strings.SplitN(scanner.Text(), ":", 2)
+		if len(split) == 2 && strings.TrimSpace(split[0]) == "Implementation-Version" {
+			return strings.TrimSpace(split[1])
+		}
+	}
+	return ""
+}
+
+// fabricMod values act as JSON import containers for fabric.mod.json files.
+type fabricMod struct {
+	Id          string
+	Name        string
+	Version     string
+	Environment string
+	Provides    []string
+	Depends     map[string]fabricRange
+	Recommends  map[string]fabricRange
+	Suggests    map[string]fabricRange
+	Breaks      map[string]fabricRange
+	Conflicts   map[string]fabricRange
+}
+
+// fabricRange values are the version ranges of a Fabric dependency, given
+// as a string or a list of alternatives.
+type fabricRange []string
+
+func (this *fabricRange) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*this = fabricRange{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(this))
+}
+
+// parseFabric returns the mod declared by a fabric.mod.json file.
+func parseFabric(data []byte) (*Metadata, error) {
+	var raw fabricMod
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("fabric.mod.json: %v", err)
+	}
+	this := Metadata{Id: raw.Id, Name: raw.Name, Version: raw.Version,
+		Loader: "fabric", Side: SideBoth, Provides: raw.Provides}
+	if raw.Environment == SideClient || raw.Environment == SideServer {
+		this.Side = raw.Environment
+	}
+	for _, el := range []struct {
+		Kind string
+		Deps map[string]fabricRange
+	}{
+		{DependRequired, raw.Depends}, {DependOptional, raw.Recommends},
+		{DependOptional, raw.Suggests}, {DependIncompatible, raw.Breaks},
+		{DependDiscouraged, raw.Conflicts},
+	} {
+		ids := []string{}
+		for id := range el.Deps {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			versions := []string{}
+			for _, v := range el.Deps[id] {
+				if v != "*" {
+					versions = append(versions, v)
+				}
+			}
+			if len(versions) < len(el.Deps[id]) {
+				// One alternative matches every version
+				versions = nil
+			}
+			this.Dependencies = append(this.Dependencies,
+				Dependency{id, el.Kind, versions, SyntaxSemver, SideBoth})
+		}
+	}
+	return &this, nil
+}
+
+// modsToml values act as TOML import containers for mods.toml and
+// neoforge.mods.toml files.
+type modsToml struct {
+	ModLoader string
+	// ClientSideOnly marks the mods as client-only (Forge 1.20 and later).
+	ClientSideOnly bool
+	Mods           []struct {
+		ModId       string
+		Version     string
+		DisplayName string
+	}
+	Dependencies map[string][]struct {
+		ModId        string
+		Mandatory    *bool
+		Type         string
+		VersionRange string
+		Side         string
+	}
+}
+
+// parseModsToml returns the mods declared by a mods.toml file for the given
+// loader. The jar version is substituted for "${file.jarVersion}".
+func parseModsToml(data []byte, version, loader string) ([]Metadata, error) {
+	var raw modsToml
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return nil, fmt.Errorf("mods.toml: %v", err)
+	}
+	mods := []Metadata{}
+	for _, el := range raw.Mods {
+		this := Metadata{Id: el.ModId, Name: el.DisplayName, Version: el.Version, Loader: loader}
+		if raw.ClientSideOnly {
+			this.Side = SideClient
+		}
+		if this.Version == "${file.jarVersion}" {
+			this.Version = version
+		} else if strings.Contains(this.Version, "${") {
+			this.Version = ""
+		}
+		for _, dep := range raw.Dependencies[el.ModId] {
+			kind := strings.ToLower(dep.Type)
+			switch {
+			case kind == "":
+				kind = DependOptional
+				if dep.Mandatory != nil && *dep.Mandatory {
+					kind = DependRequired
+				}
+			case kind != DependRequired && kind != DependIncompatible && kind != DependDiscouraged:
+				kind = DependOptional
+			}
+			side := strings.ToLower(dep.Side)
+			if side == "" {
+				side = SideBoth
+			}
+			var versions []string
+			if dep.VersionRange != "" && dep.VersionRange != "*" {
+				versions = []string{dep.VersionRange}
+			}
+			this.Dependencies = append(this.Dependencies,
+				Dependency{dep.ModId, kind, versions, SyntaxMaven, side})
+		}
+		mods = append(mods, this)
+	}
+	return mods, nil
+}
+
+// mcmodInfo values act as JSON import containers for the mods listed in
+// mcmod.info files.
+type mcmodInfo struct {
+	Modid        string
+	Name         string
+	Version      string
+	Mcversion    string
+	RequiredMods []string
+}
+
+// parseMcmodInfo returns the mods declared by an mcmod.info file, which is
+// either a list of mods or an object with a "modList".
+func parseMcmodInfo(data []byte) ([]Metadata, error) {
+	var list []mcmodInfo
+	if err := json.Unmarshal(data, &list); err != nil {
+		var wrapped struct{ ModList []mcmodInfo }
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("mcmod.info: %v", err)
+		}
+		list = wrapped.ModList
+	}
+	mods := []Metadata{}
+	for _, el := range list {
+		this := Metadata{Id: el.Modid, Name: el.Name, Version: el.Version,
+			Loader: "forge", Minecraft: el.Mcversion}
+		for _, v := range []*string{&this.Version, &this.Minecraft} {
+			if strings.Contains(*v, "${") {
+				*v = ""
+			}
+		}
+		for _, dep := range el.RequiredMods {
+			// Required mods have the form "<modid>[@<range>]"
+			split := strings.SplitN(dep, "@", 2)
+			var versions []string
+			if len(split) == 2 && split[1] != "" {
+				versions = []string{split[1]}
+			}
+			this.Dependencies = append(this.Dependencies,
+				Dependency{split[0], DependRequired, versions, SyntaxMaven, SideBoth})
+		}
+		mods = append(mods, this)
+	}
+	return mods, nil
+}
@@ -0,0 +1,324 @@
+package mod
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// zipJar returns a jar with the given entries, by name.
+func zipJar(t *testing.T, entries map[string]string) []byte {
+	names := []string{}
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(entries[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readEntries returns the mods declared by a jar "test.jar" with the given
+// entries.
+func readEntries(t *testing.T, entries map[string]string) []Metadata {
+	data := zipJar(t, entries)
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mods, err := readJar(r, "test.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mods
+}
+
+func TestReadMetadata(t *testing.T) {
+	jeiToml := `modLoader = "javafml"
+[[mods]]
+modId = "jei"
+version = "${file.jarVersion}"
+displayName = "Just Enough Items"
+`
+	inner := zipJar(t, map[string]string{"fabric.mod.json": `{"id": "cloth-config", "version": "11.1.106"}`})
+	for _, test := range []struct {
+		Name    string
+		Entries map[string]string
+		// Want lists the "<id> <version> <loader> <side>" of each mod
+		Want []string
+	}{
+		{"jar version", map[string]string{
+			"META-INF/mods.toml":   jeiToml,
+			"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\r\nImplementation-Version: 15.2.0.27\r\n",
+		}, []string{"jei 15.2.0.27 forge "}},
+		{"missing jar version", map[string]string{"META-INF/mods.toml": jeiToml},
+			[]string{"jei  forge "}},
+		{"unknown placeholder", map[string]string{"META-INF/mods.toml": `[[mods]]
+modId = "create"
+version = "${global.version}"
+`}, []string{"create  forge "}},
+		{"client only", map[string]string{"META-INF/mods.toml": `clientSideOnly = true
+[[mods]]
+modId = "oculus"
+version = "1.6.9"
+`}, []string{"oculus 1.6.9 forge client"}},
+		{"several loaders", map[string]string{
+			"META-INF/mods.toml":          "[[mods]]\nmodId = \"ftblib\"\nversion = \"1.0\"\n",
+			"META-INF/neoforge.mods.toml": "[[mods]]\nmodId = \"ftblib\"\nversion = \"1.0\"\n",
+		}, []string{"ftblib 1.0 neoforge ", "ftblib 1.0 forge "}},
+		{"fabric", map[string]string{"fabric.mod.json": `{"id": "sodium", "version": "0.5.3",
+			"environment": "client"}`}, []string{"sodium 0.5.3 fabric client"}},
+		{"fabric any side", map[string]string{"fabric.mod.json": `{"id": "lithium", "version": "0.11.2",
+			"environment": "*"}`}, []string{"lithium 0.11.2 fabric both"}},
+		{"mcmod.info list", map[string]string{"mcmod.info": `[{"modid": "jei", "version": "4.16.1",
+			"mcversion": "1.12.2"}]`}, []string{"jei 4.16.1 forge "}},
+		{"mcmod.info modList", map[string]string{"mcmod.info": `{"modListVersion": 2,
+			"modList": [{"modid": "ic2", "version": "${version}"}]}`}, []string{"ic2  forge "}},
+		{"malformed mcmod.info", map[string]string{"mcmod.info": `[{"modid": "broken",}]`}, []string{}},
+		{"mcmod.info with mods.toml", map[string]string{
+			"META-INF/mods.toml": "[[mods]]\nmodId = \"jei\"\nversion = \"15.2\"\n",
+			"mcmod.info":         `[{"modid": "jei", "version": "4.16.1"}]`,
+		}, []string{"jei 15.2 forge "}},
+		{"library", map[string]string{"com/example/Library.class": ""}, []string{}},
+		{"nested jarjar", map[string]string{
+			"META-INF/mods.toml":                  "[[mods]]\nmodId = \"create\"\nversion = \"0.5.1\"\n",
+			"META-INF/jarjar/cloth-config.jar":    string(inner),
+			"META-INF/jarjar/metadata.json":       `{"jars": []}`,
+			"META-INF/jars/cloth-config.jar":      string(inner),
+			"META-INF/libraries/cloth-config.jar": string(inner),
+		}, []string{"create 0.5.1 forge ", "cloth-config 11.1.106 fabric both",
+			"cloth-config 11.1.106 fabric both"}},
+	} {
+		mods := readEntries(t, test.Entries)
+		got := []string{}
+		for _, el := range mods {
+			got = append(got, el.Id+" "+el.Version+" "+el.Loader+" "+el.Side)
+			if el.File != "test.jar" {
+				t.Errorf("%s: mod %s has file %s, want the outer jar", test.Name, el.Id, el.File)
+			}
+		}
+		if !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("%s: got mods %q, want %q", test.Name, got, test.Want)
+		}
+	}
+}
+
+func TestParseFabricDependencies(t *testing.T) {
+	meta, err := parseFabric([]byte(`{"id": "modmenu", "version": "7.2.2",
+		"provides": ["menu"],
+		"depends": {"fabricloader": ">=0.14.0", "minecraft": ["1.20", "1.20.1"], "fabric-api": "*"},
+		"recommends": {"cloth-config": ["*", ">=11"]},
+		"suggests": {"sodium": ">=0.5"},
+		"breaks": {"optifabric": "<1.13"},
+		"conflicts": {"iris": "*"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Dependency{
+		{"fabric-api", DependRequired, nil, SyntaxSemver, SideBoth},
+		{"fabricloader", DependRequired, []string{">=0.14.0"}, SyntaxSemver, SideBoth},
+		{"minecraft", DependRequired, []string{"1.20", "1.20.1"}, SyntaxSemver, SideBoth},
+		// An alternative matching every version drops the others
+		{"cloth-config", DependOptional, nil, SyntaxSemver, SideBoth},
+		{"sodium", DependOptional, []string{">=0.5"}, SyntaxSemver, SideBoth},
+		{"optifabric", DependIncompatible, []string{"<1.13"}, SyntaxSemver, SideBoth},
+		{"iris", DependDiscouraged, nil, SyntaxSemver, SideBoth},
+	}
+	if !reflect.DeepEqual(meta.Dependencies, want) {
+		t.Errorf("got dependencies %+v, want %+v", meta.Dependencies, want)
+	}
+	if !reflect.DeepEqual(meta.Provides, []string{"menu"}) {
+		t.Errorf("got provides %v, want [menu]", meta.Provides)
+	}
+	meta.constraints()
+	if meta.Minecraft != "1.20 || 1.20.1" || meta.LoaderVersion != ">=0.14.0" {
+		t.Errorf("got Minecraft %q and loader %q constraints", meta.Minecraft, meta.LoaderVersion)
+	}
+}
+
+func TestParseModsTomlDependencies(t *testing.T) {
+	mods, err := parseModsToml([]byte(`[[mods]]
+modId = "create"
+version = "0.5.1"
+
+[[dependencies.create]]
+modId = "forge"
+mandatory = true
+versionRange = "[47,)"
+
+[[dependencies.create]]
+modId = "minecraft"
+mandatory = true
+versionRange = "[1.20.1,1.20.2)"
+
+[[dependencies.create]]
+modId = "jei"
+mandatory = false
+versionRange = "*"
+side = "CLIENT"
+
+[[dependencies.create]]
+modId = "flywheel"
+type = "required"
+versionRange = "[0.6.10]"
+
+[[dependencies.create]]
+modId = "curios"
+type = "optional"
+mandatory = true
+
+[[dependencies.create]]
+modId = "optifine"
+type = "incompatible"
+
+[[dependencies.create]]
+modId = "rubidium"
+type = "discouraged"
+
+[[dependencies.create]]
+modId = "lib"
+type = "embedded"
+
+[[dependencies.create]]
+modId = "ponder"
+`), "", "forge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 1 {
+		t.Fatalf("got %d mods, want 1", len(mods))
+	}
+	want := []Dependency{
+		{"forge", DependRequired, []string{"[47,)"}, SyntaxMaven, SideBoth},
+		{"minecraft", DependRequired, []string{"[1.20.1,1.20.2)"}, SyntaxMaven, SideBoth},
+		{"jei", DependOptional, nil, SyntaxMaven, SideClient},
+		{"flywheel", DependRequired, []string{"[0.6.10]"}, SyntaxMaven, SideBoth},
+		// The type takes precedence over the legacy mandatory flag
+		{"curios", DependOptional, nil, SyntaxMaven, SideBoth},
+		{"optifine", DependIncompatible, nil, SyntaxMaven, SideBoth},
+		{"rubidium", DependDiscouraged, nil, SyntaxMaven, SideBoth},
+		{"lib", DependOptional, nil, SyntaxMaven, SideBoth},
+		{"ponder", DependOptional, nil, SyntaxMaven, SideBoth},
+	}
+	if !reflect.DeepEqual(mods[0].Dependencies, want) {
+		t.Errorf("got dependencies %+v, want %+v", mods[0].Dependencies, want)
+	}
+	mods[0].constraints()
+	if mods[0].Minecraft != "[1.20.1,1.20.2)" || mods[0].LoaderVersion != "[47,)" {
+		t.Errorf("got Minecraft %q and loader %q constraints", mods[0].Minecraft, mods[0].LoaderVersion)
+	}
+}
+
+func TestParseMcmodInfoDependencies(t *testing.T) {
+	mods, err := parseMcmodInfo([]byte(`[{"modid": "jeresources", "version": "0.9.2",
+		"mcversion": "1.12.2", "requiredMods": ["jei@[4.15,)", "forge"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Dependency{
+		{"jei", DependRequired, []string{"[4.15,)"}, SyntaxMaven, SideBoth},
+		{"forge", DependRequired, nil, SyntaxMaven, SideBoth},
+	}
+	if len(mods) != 1 || !reflect.DeepEqual(mods[0].Dependencies, want) {
+		t.Errorf("got mods %+v, want dependencies %+v", mods, want)
+	}
+	if mods[0].Minecraft != "1.12.2" {
+		t.Errorf("got Minecraft %q, want 1.12.2", mods[0].Minecraft)
+	}
+}
+
+func TestForLoader(t *testing.T) {
+	mods := []Metadata{
+		{Id: "ftblib", Loader: "forge", File: "ftblib.jar"},
+		{Id: "ftblib", Loader: "neoforge", File: "ftblib.jar"},
+		{Id: "ftblib", Loader: "fabric", File: "ftblib.jar"},
+		{Id: "jei", Loader: "forge", File: "jei.jar"},
+		{Id: "sodium", Loader: "fabric", File: "sodium.jar"},
+	}
+	for _, test := range []struct {
+		Loader string
+		Want   []string
+	}{
+		{"forge", []string{"forge ftblib", "forge jei", "fabric sodium"}},
+		{"neoforge", []string{"neoforge ftblib", "forge jei", "fabric sodium"}},
+		{"fabric", []string{"fabric ftblib", "forge jei", "fabric sodium"}},
+	} {
+		got := []string{}
+		for _, el := range ForLoader(mods, test.Loader) {
+			got = append(got, el.Loader+" "+el.Id)
+		}
+		if !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("%s: got %q, want %q", test.Loader, got, test.Want)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		"sodium.jar": zipJar(t, map[string]string{"fabric.mod.json": `{"id": "sodium", "name": "Sodium",
+			"version": "0.5.3", "environment": "client",
+			"depends": {"minecraft": "1.20.1", "fabricloader": ">=0.12.0"}}`}),
+		"jei.jar": zipJar(t, map[string]string{"META-INF/mods.toml": `[[mods]]
+modId = "jei"
+version = "15.2.0.27"
+[[dependencies.jei]]
+modId = "minecraft"
+mandatory = true
+versionRange = "[1.20.1,1.20.2)"
+`}),
+		"library.jar": zipJar(t, map[string]string{"com/example/Library.class": ""}),
+		"readme.txt":  []byte("not a jar"),
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mods, err := Inspect(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, el := range mods {
+		got = append(got, strings.Join([]string{filepath.Base(el.File), el.Id, el.Loader,
+			el.Side, el.Minecraft, el.LoaderVersion}, " "))
+	}
+	// Jars are listed by name, including those without metadata
+	want := []string{
+		"jei.jar jei forge  [1.20.1,1.20.2) ",
+		"library.jar     ",
+		"sodium.jar sodium fabric client 1.20.1 >=0.12.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got mods %q, want %q", got, want)
+	}
+
+	if mods, err = Inspect(filepath.Join(dir, "sodium.jar")); err != nil {
+		t.Fatal(err)
+	} else if len(mods) != 1 || mods[0].Name != "Sodium" {
+		t.Errorf("got mods %+v, want sodium", mods)
+	}
+	if _, err := Inspect(filepath.Join(dir, "missing.jar")); err == nil {
+		t.Error("missing jar inspected")
+	}
+	if _, err := Inspect(filepath.Join(dir, "readme.txt")); err == nil {
+		t.Error("file that is not a jar inspected")
+	}
+}
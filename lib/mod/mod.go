@@ -7,9 +7,6 @@ import (
 	"fmt"
 	"github.com/faceless-saint/m3/lib/net"
 	"hash"
-	"io/ioutil"
-	"net/http"
-	"regexp"
 	"strings"
 )
 
@@ -21,16 +18,43 @@ type Raw struct {
 	Checksum string
 	Url      string
 	Curse    string
+	Modrinth string
+	// Maven is a Maven coordinate, used with the optional Repository.
+	Maven      string
+	Repository string
+	// GitHub is a GitHub repository, used with the optional Tag and Asset.
+	GitHub string
+	Tag    string
+	Asset  string
+	// Path is a local file relative to the spec's directory.
+	Path string
+	// Side is the side the mod is installed on: "client", "server" or
+	// "both" (default).
+	Side string
+	// Optional mods are only installed if selected. Default selects them
+	// unless deselected.
+	Optional bool
+	Default  bool
 }
 
 // New initializes a new mod type from the imported Raw value. The
 // specific mod type is chosen dynamically based on the defined data
 // fields, using the most feature-rich implementation supported.
 func New(mod *Raw) (net.Downloadable, error) {
+	return NewFor(mod, "", "")
+}
+
+// NewFor initializes a new mod type like New. Mods tracking the latest
+// release pick the latest one for the given loader and Minecraft version,
+// if set.
+func NewFor(mod *Raw, loader, minecraft string) (net.Downloadable, error) {
 	if mod.Name == "" {
 		// Missing required 'name' property.
 		return nil, &InitError{*mod, "mod init error: missing required property 'name'"}
 	}
+	if !ValidSide(mod.Side) {
+		return nil, &InitError{*mod, "mod init error: 'side' property must be one of 'client', 'server', 'both'"}
+	}
 	// Determine checksum and hashing algorithm
 	hashsum := strings.SplitN(mod.Checksum, ":", 2)
 	if len(hashsum) < 2 {
@@ -41,7 +65,7 @@ func New(mod *Raw) (net.Downloadable, error) {
 	if err != nil {
 		return nil, err
 	}
-	base := RemoteMod{mod.Name, mod.Version, hashsum[1], h, mod.Url}
+	base := RemoteMod{mod.Name, mod.Version, hashsum[1], h, mod.Url, mod.Side}
 
 	/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
 		 * When defining new mod types, add their initialization checks  *
@@ -49,19 +73,35 @@ func New(mod *Raw) (net.Downloadable, error) {
 	     * specified in decending order of preference.                   *
 		 * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
 	switch {
+	// Use a ModrinthMod value.
+	case mod.Modrinth != "":
+		return NewModrinthMod(base, mod.Modrinth, loader, minecraft)
+
 	// Use a CurseMod value.
 	case mod.Curse != "":
-		curseMod := CurseMod{base, mod.Curse}
+		curseMod := CurseMod{RemoteMod: base, Curse: mod.Curse, Loader: loader, Minecraft: minecraft}
 		err := curseMod.Init()
 		return &curseMod, err
 
+	// Use a GitHubReleaseMod value.
+	case mod.GitHub != "":
+		return NewGitHubReleaseMod(base, mod.GitHub, mod.Tag, mod.Asset)
+
+	// Use a MavenMod value.
+	case mod.Maven != "":
+		return NewMavenMod(base, mod.Maven, mod.Repository)
+
+	// Use a LocalMod value.
+	case mod.Path != "":
+		return NewLocalMod(base, mod.Path)
+
 	// Use a basic RemoteMod value. (last resort)
 	case mod.Url != "":
 		return &base, nil
 
 	// Error: no mod implementations were satisfied.
 	default:
-		return nil, &InitError{*mod, "mod init error: not enough data to implement Mod - need one of 'curse', 'url'"}
+		return nil, &InitError{*mod, "mod init error: not enough data to implement Mod - need one of 'modrinth', 'curse', 'github', 'maven', 'path', 'url'"}
 	}
 }
 
@@ -72,11 +112,25 @@ type RemoteMod struct {
 	checksum string
 	hash     hash.Hash
 	url      string
+	side     string
+}
+
+// Named is the interface for mods that know the name they are defined
+// with in the spec.
+type Named interface {
+	ModName() string
 }
 
+func (this *RemoteMod) ModName() string  { return this.Name }
 func (this *RemoteMod) Url() string      { return this.url }
 func (this *RemoteMod) Checksum() string { return this.checksum }
 func (this *RemoteMod) Hash() hash.Hash  { return this.hash }
+func (this *RemoteMod) Side() string {
+	if this.side == "" {
+		return SideBoth
+	}
+	return this.side
+}
 func (this *RemoteMod) Filename() string {
 	if this.Version != "" {
 		return fmt.Sprintf("%s-%s-%s.jar",
@@ -86,66 +140,3 @@ func (this *RemoteMod) Filename() string {
 			this.Name, net.Digest(this.Url(), 6))
 	}
 }
-
-// CurseMod values represent mod files hosted on Curseforge. This adds
-// extra functionality inluding update checking. The "Curse" property
-// can either be the full download URL or the Curse file ID.
-type CurseMod struct {
-	RemoteMod
-	// Curse is the file download ID on Curseforge.
-	Curse string
-}
-
-// Curseforge download URL is constructed as follows:
-//    url_head + {mod_name} + url_mid + {file_id} + url_tail
-const curse_url_head = "https://minecraft.curseforge.com/projects/"
-const curse_url_mid = "/files/"
-const curse_url_tail = "/download"
-
-// Init validates the Curse property for the CurseMod as either a Curse
-// download URL or a file ID. It then sets the download URL accordingly.
-func (this *CurseMod) Init() error {
-	// Match Curse field to either a URL or a file ID
-	re_url := regexp.MustCompile(curse_url_head + this.Name +
-		curse_url_mid + "[0-9]+" + curse_url_tail)
-	re_id := regexp.MustCompile("^[0-9]+$")
-	if re_url.Match([]byte(this.Curse)) {
-		// Curse field contains a Curseforge URL - convert to file ID
-		this.Curse = string(strings.Replace(strings.Replace(this.Curse,
-			curse_url_head+this.Name+curse_url_mid, "", 1),
-			curse_url_tail, "", 1))
-	} else if !re_id.Match([]byte(this.Curse)) {
-		// Curse field is not a valid file ID
-		return &InitError{Raw{Curse: this.Curse},
-			"mod init error: 'curse' property is invalid"}
-	}
-
-	// Set download URL
-	this.url = curse_url_head + this.Name + curse_url_mid +
-		this.Curse + curse_url_tail
-	return nil
-}
-
-// GetLatest gets the latest file ID for the Mod from Curseforge.
-func (this *CurseMod) GetLatest() (string, error) {
-	re := regexp.MustCompile("href=\"/projects/" +
-		this.Name + "/files/[0-9]+/download")
-	resp, err := http.Get("https://minecraft.curseforge.com/projects/" +
-		this.Name + "/files?sort=releasetype")
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	page, err := ioutil.ReadAll(resp.Body)
-	return string(strings.Replace(strings.Replace(string(re.Find(page)),
-		"href=\"/projects/"+this.Name+"/files/", "", 1),
-		"/download", "", 1)), nil
-}
-
-// SetTarget sets the CurseMod value to track the given remote file from
-// Curseforge. If a complete Curseforge URL is given, the file id will
-// be extracted from it.
-func (this *CurseMod) SetTarget(curse string) error {
-	this.Curse = curse
-	return this.Init()
-}
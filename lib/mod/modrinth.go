@@ -0,0 +1,161 @@
+package mod
+
+import (
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"net/url"
+	"strings"
+)
+
+// ModrinthApi is the base URL of the Modrinth v2 API.
+var ModrinthApi = "https://api.modrinth.com/v2"
+
+// ModrinthMod values represent mod files hosted on Modrinth. The
+// "Modrinth" property has the form "<project>:<version>", where the
+// project is a Modrinth project slug or ID and the version is a Modrinth
+// version ID. If the version is omitted, the latest version is used.
+type ModrinthMod struct {
+	RemoteMod
+	// Project is the Modrinth project slug or ID.
+	Project string
+	// VersionId is the Modrinth version ID.
+	VersionId string
+	// Loader and Minecraft restrict the latest version to the given loader
+	// and Minecraft version, if set.
+	Loader    string
+	Minecraft string
+	// Size is the published size of the file in bytes.
+	Size int64
+}
+
+// modrinthVersion values act as JSON import containers for Modrinth
+// version objects.
+type modrinthVersion struct {
+	Id             string
+	Project_id     string
+	Version_number string
+	Files          []modrinthFile
+}
+
+// modrinthFile values act as JSON import containers for Modrinth file
+// objects.
+type modrinthFile struct {
+	Hashes   map[string]string
+	Url      string
+	Filename string
+	Primary  bool
+	Size     int64
+}
+
+// NewModrinthMod returns a new ModrinthMod for the given "<project>" or
+// "<project>:<version>" reference, based on the given RemoteMod. Without a
+// version, the latest version for the loader and Minecraft version is used.
+func NewModrinthMod(base RemoteMod, ref, loader, minecraft string) (*ModrinthMod, error) {
+	split := strings.SplitN(ref, ":", 2)
+	this := ModrinthMod{RemoteMod: base, Project: split[0], Loader: loader, Minecraft: minecraft}
+	if len(split) == 2 {
+		this.VersionId = split[1]
+	}
+	return &this, this.Init()
+}
+
+// Init resolves the ModrinthMod's version through the Modrinth API and
+// sets the download URL accordingly. If no reference checksum was given,
+// the published SHA512 checksum is used.
+func (this *ModrinthMod) Init() error {
+	if this.Project == "" {
+		return &InitError{Raw{Name: this.Name},
+			"mod init error: 'modrinth' property is missing a project"}
+	}
+	if this.VersionId == "" {
+		latest, err := this.GetLatest()
+		if err != nil {
+			return err
+		}
+		this.VersionId = latest
+	}
+	var version modrinthVersion
+	err := net.GetJSON(ModrinthApi+"/version/"+url.PathEscape(this.VersionId), nil, &version)
+	if err != nil {
+		return err
+	}
+	if version.Project_id != this.Project {
+		// Project may be given as a slug - resolve it to an ID
+		var project struct{ Id string }
+		err := net.GetJSON(ModrinthApi+"/project/"+url.PathEscape(this.Project), nil, &project)
+		if err != nil {
+			return err
+		}
+		if version.Project_id != project.Id {
+			return &InitError{Raw{Name: this.Name}, fmt.Sprintf(
+				"mod init error: version %s does not belong to project %s",
+				this.VersionId, this.Project)}
+		}
+	}
+	file, err := version.primary()
+	if err != nil {
+		return err
+	}
+	// Files are named like other mods rather than by their published name
+	this.url, this.Size = file.Url, file.Size
+	if this.Version == "" {
+		this.Version = version.Version_number
+	}
+	if this.checksum == "" && file.Hashes["sha512"] != "" {
+		this.checksum, this.hash = file.Hashes["sha512"], sha512.New()
+	}
+	return nil
+}
+
+// GetLatest gets the latest version ID for the mod from Modrinth, for the
+// ModrinthMod's loader and Minecraft version if set.
+func (this *ModrinthMod) GetLatest() (string, error) {
+	query := url.Values{}
+	if this.Loader != "" {
+		loaders, _ := json.Marshal([]string{this.Loader})
+		query.Set("loaders", string(loaders))
+	}
+	if this.Minecraft != "" {
+		versions, _ := json.Marshal([]string{this.Minecraft})
+		query.Set("game_versions", string(versions))
+	}
+	versions := []modrinthVersion{}
+	err := net.GetJSON(ModrinthApi+"/project/"+url.PathEscape(this.Project)+"/version?"+query.Encode(),
+		nil, &versions)
+	if err != nil {
+		return "", err
+	} else if len(versions) == 0 {
+		return "", fmt.Errorf("error: no versions found for Modrinth project %s", this.Project)
+	}
+	return versions[0].Id, nil
+}
+
+// SetTarget sets the ModrinthMod value to track the given Modrinth
+// version ID.
+func (this *ModrinthMod) SetTarget(version string) error {
+	this.VersionId = version
+	this.Version, this.checksum = "", ""
+	return this.Init()
+}
+
+// FileSize returns the published size of the ModrinthMod's file, which the
+// download is checked against.
+func (this *ModrinthMod) FileSize() int64 {
+	return this.Size
+}
+
+// primary returns the primary file of the version, or the first file if
+// none is marked as primary.
+func (this *modrinthVersion) primary() (*modrinthFile, error) {
+	if len(this.Files) == 0 {
+		return nil, fmt.Errorf("error: Modrinth version %s has no files", this.Id)
+	}
+	for i := range this.Files {
+		if this.Files[i].Primary {
+			return &this.Files[i], nil
+		}
+	}
+	return &this.Files[0], nil
+}
@@ -0,0 +1,158 @@
+package mod
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// serve starts a stand-in API server answering each "METHOD /path" route
+// with its JSON body, and 404 otherwise. The requests received are counted
+// by route, and by route and query if there is one.
+func serve(t *testing.T, routes map[string]string) map[string]int {
+	received := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+		received[route]++
+		if r.URL.RawQuery != "" {
+			received[route+"?"+r.URL.RawQuery]++
+		}
+		body, ok := routes[route]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	api, curse, key := ModrinthApi, CurseApi, CurseApiKey
+	ModrinthApi, CurseApi, CurseApiKey = srv.URL, srv.URL, "key"
+	t.Cleanup(func() { ModrinthApi, CurseApi, CurseApiKey = api, curse, key })
+	return received
+}
+
+// modrinthVersionJSON is a Modrinth version of project "AABBCCDD", with a
+// secondary file listed before the primary file.
+const modrinthVersionJSON = `{
+	"id": "VERSION1",
+	"project_id": "AABBCCDD",
+	"version_number": "1.2.3",
+	"files": [
+		{"hashes": {"sha512": "5ec0dd"}, "url": "https://cdn.modrinth.com/data/AABBCCDD/versions/VERSION1/sodium-sources.jar",
+		 "filename": "sodium-sources.jar", "primary": false, "size": 10},
+		{"hashes": {"sha512": "ba5e12", "sha1": "0ab1"}, "url": "https://cdn.modrinth.com/data/AABBCCDD/versions/VERSION1/sodium.jar",
+		 "filename": "sodium.jar", "primary": true, "size": 20}
+	]
+}`
+
+func TestModrinthSlug(t *testing.T) {
+	received := serve(t, map[string]string{
+		"GET /version/VERSION1":  modrinthVersionJSON,
+		"GET /project/sodium":    `{"id": "AABBCCDD", "slug": "sodium"}`,
+		"GET /project/other-mod": `{"id": "EEFF0011", "slug": "other-mod"}`,
+	})
+	dl, err := New(&Raw{Name: "sodium", Modrinth: "sodium:VERSION1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received["GET /project/sodium"] != 1 {
+		t.Error("project slug was not resolved to an ID")
+	}
+	mod := dl.(*ModrinthMod)
+	if mod.Version != "1.2.3" {
+		t.Errorf("got version %q, want 1.2.3", mod.Version)
+	}
+
+	_, err = New(&Raw{Name: "other", Modrinth: "other-mod:VERSION1"})
+	if err == nil {
+		t.Error("version of another project accepted")
+	}
+}
+
+func TestModrinthPrimaryFile(t *testing.T) {
+	received := serve(t, map[string]string{"GET /version/VERSION1": modrinthVersionJSON})
+	dl, err := New(&Raw{Name: "sodium", Modrinth: "AABBCCDD:VERSION1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received["GET /project/AABBCCDD"] != 0 {
+		t.Error("project ID was looked up")
+	}
+	mod := dl.(*ModrinthMod)
+	if mod.Url() != "https://cdn.modrinth.com/data/AABBCCDD/versions/VERSION1/sodium.jar" {
+		t.Errorf("got URL %s, want the primary file", mod.Url())
+	}
+	if mod.FileSize() != 20 {
+		t.Errorf("got file of %d bytes, want the primary file of 20 bytes", mod.FileSize())
+	}
+}
+
+func TestModrinthChecksum(t *testing.T) {
+	serve(t, map[string]string{"GET /version/VERSION1": modrinthVersionJSON})
+	dl, err := New(&Raw{Name: "sodium", Modrinth: "AABBCCDD:VERSION1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Checksum() != "ba5e12" || dl.Hash().Size() != 64 {
+		t.Errorf("got checksum %s, want the SHA512 hash of the primary file", dl.Checksum())
+	}
+
+	// Reference checksums take precedence
+	dl, err = New(&Raw{Name: "sodium", Modrinth: "AABBCCDD:VERSION1", Checksum: "sha1:0ab1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Checksum() != "0ab1" || dl.Hash().Size() != 20 {
+		t.Errorf("got checksum %s, want the reference SHA1 checksum", dl.Checksum())
+	}
+}
+
+func TestModrinthLatest(t *testing.T) {
+	serve(t, map[string]string{
+		"GET /project/AABBCCDD/version": `[{"id": "VERSION1"}, {"id": "VERSION0"}]`,
+		"GET /version/VERSION1":         modrinthVersionJSON,
+	})
+	dl, err := New(&Raw{Name: "sodium", Modrinth: "AABBCCDD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.(*ModrinthMod).VersionId != "VERSION1" {
+		t.Errorf("got version %s, want the latest", dl.(*ModrinthMod).VersionId)
+	}
+}
+
+func TestModrinthLatestFiltered(t *testing.T) {
+	received := serve(t, map[string]string{
+		"GET /project/AABBCCDD/version": `[{"id": "VERSION1"}]`,
+		"GET /version/VERSION1":         modrinthVersionJSON,
+	})
+	if _, err := NewFor(&Raw{Name: "sodium", Modrinth: "AABBCCDD"}, "fabric", "1.20.1"); err != nil {
+		t.Fatal(err)
+	}
+	query := url.Values{"loaders": {`["fabric"]`}, "game_versions": {`["1.20.1"]`}}
+	if received["GET /project/AABBCCDD/version?"+query.Encode()] != 1 {
+		t.Errorf("latest version not filtered by loader and Minecraft version: %v", received)
+	}
+}
+
+func TestModrinthSetTarget(t *testing.T) {
+	serve(t, map[string]string{
+		"GET /version/VERSION0": `{"id": "VERSION0", "project_id": "AABBCCDD", "version_number": "1.2.2",
+			"files": [{"hashes": {"sha512": "01d5e1"}, "url": "https://cdn.modrinth.com/sodium-old.jar", "size": 15}]}`,
+		"GET /version/VERSION1": modrinthVersionJSON,
+	})
+	dl, err := New(&Raw{Name: "sodium", Modrinth: "AABBCCDD:VERSION0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod := dl.(*ModrinthMod)
+	if err := mod.SetTarget("VERSION1"); err != nil {
+		t.Fatal(err)
+	}
+	if mod.Version != "1.2.3" || mod.Checksum() != "ba5e12" || mod.FileSize() != 20 {
+		t.Errorf("got version %s with checksum %s, want those of VERSION1", mod.Version, mod.Checksum())
+	}
+}
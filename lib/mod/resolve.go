@@ -0,0 +1,109 @@
+package mod
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/net"
+	"net/url"
+	"strings"
+)
+
+// CurseForge mod loader type identifiers, by loader name.
+var curse_loader_types = map[string]int{"forge": 1, "fabric": 4, "neoforge": 6}
+
+// CurseForge identifiers of Minecraft and its mods.
+const (
+	curse_game_minecraft = 432
+	curse_class_mods     = 6
+)
+
+// Resolve returns a new mod providing the dependency for the given loader
+// and Minecraft version. Modrinth is searched first, then CurseForge if an
+// API key is set. The newest version matching the dependency's ranges is
+// preferred, or else the newest version.
+func Resolve(dep Dependency, loader, minecraft string) (net.Downloadable, error) {
+	raw, err := resolveModrinth(dep, loader, minecraft)
+	if err == nil && raw == nil && CurseApiKey != "" {
+		raw, err = resolveCurse(dep, loader, minecraft)
+	}
+	if err != nil {
+		return nil, err
+	} else if raw == nil {
+		return nil, fmt.Errorf("error: no %s %s release of %s found", loader, minecraft, dep.Id)
+	}
+	return New(raw)
+}
+
+// resolveModrinth returns the Raw mod of the Modrinth version providing the
+// dependency, or nil if there is none. Projects are matched by slug.
+func resolveModrinth(dep Dependency, loader, minecraft string) (*Raw, error) {
+	facets, _ := json.Marshal([][]string{{"project_type:mod"},
+		{"categories:" + loader}, {"versions:" + minecraft}})
+	var search struct {
+		Hits []struct{ Slug string }
+	}
+	err := net.GetJSON(fmt.Sprintf("%s/search?query=%s&facets=%s&limit=10", ModrinthApi,
+		url.QueryEscape(dep.Id), url.QueryEscape(string(facets))), nil, &search)
+	if err != nil {
+		return nil, err
+	}
+	project := ""
+	for _, el := range search.Hits {
+		if strings.EqualFold(el.Slug, dep.Id) || strings.EqualFold(strings.Replace(el.Slug, "-", "_", -1), dep.Id) {
+			project = el.Slug
+			break
+		}
+	}
+	if project == "" {
+		return nil, nil
+	}
+	loaders, _ := json.Marshal([]string{loader})
+	versions, _ := json.Marshal([]string{minecraft})
+	list := []modrinthVersion{}
+	err = net.GetJSON(fmt.Sprintf("%s/project/%s/version?loaders=%s&game_versions=%s",
+		ModrinthApi, url.PathEscape(project), url.QueryEscape(string(loaders)),
+		url.QueryEscape(string(versions))), nil, &list)
+	if err != nil {
+		return nil, err
+	} else if len(list) == 0 {
+		return nil, nil
+	}
+	chosen := list[0]
+	for _, el := range list {
+		if dep.Matches(el.Version_number) {
+			chosen = el
+			break
+		}
+	}
+	return &Raw{Name: dep.Id, Modrinth: project + ":" + chosen.Id}, nil
+}
+
+// resolveCurse returns the Raw mod of the CurseForge file providing the
+// dependency, or nil if there is none. Mods are matched by slug.
+func resolveCurse(dep Dependency, loader, minecraft string) (*Raw, error) {
+	filter := fmt.Sprintf("gameVersion=%s&modLoaderType=%d",
+		url.QueryEscape(minecraft), curse_loader_types[loader])
+	var search struct {
+		Data []struct {
+			Id   int
+			Slug string
+		}
+	}
+	err := net.GetJSON(fmt.Sprintf("%s/v1/mods/search?gameId=%d&classId=%d&slug=%s&%s",
+		CurseApi, curse_game_minecraft, curse_class_mods,
+		url.QueryEscape(strings.Replace(dep.Id, "_", "-", -1)), filter), curseHeader(), &search)
+	if err != nil {
+		return nil, err
+	} else if len(search.Data) == 0 {
+		return nil, nil
+	}
+	var files struct{ Data []curseFile }
+	err = net.GetJSON(fmt.Sprintf("%s/v1/mods/%d/files?%s&pageSize=1",
+		CurseApi, search.Data[0].Id, filter), curseHeader(), &files)
+	if err != nil {
+		return nil, err
+	} else if len(files.Data) == 0 {
+		return nil, nil
+	}
+	return &Raw{Name: dep.Id, Curse: fmt.Sprintf("%d:%d", search.Data[0].Id, files.Data[0].Id)}, nil
+}
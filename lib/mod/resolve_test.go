@@ -0,0 +1,64 @@
+package mod
+
+import (
+	"testing"
+)
+
+func TestResolveModrinth(t *testing.T) {
+	received := serve(t, map[string]string{
+		"GET /search": `{"hits": [{"slug": "cloth-config-extra"}, {"slug": "cloth-config"}]}`,
+		"GET /project/cloth-config/version": `[
+			{"id": "VERSION3", "version_number": "12.0.109"},
+			{"id": "VERSION2", "version_number": "11.1.106"},
+			{"id": "VERSION1", "version_number": "11.0.99"}
+		]`,
+		"GET /project/cloth-config": `{"id": "CLOTHID1", "slug": "cloth-config"}`,
+		"GET /version/VERSION2": `{"id": "VERSION2", "project_id": "CLOTHID1", "version_number": "11.1.106",
+			"files": [{"hashes": {"sha512": "c1075e"}, "url": "https://cdn.modrinth.com/cloth-config.jar", "primary": true}]}`,
+	})
+	dep := Dependency{"cloth_config", DependRequired, []string{">=11.1 <12"}, SyntaxSemver, SideBoth}
+	dl, err := Resolve(dep, "fabric", "1.20.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod := dl.(*ModrinthMod)
+	if mod.Project != "cloth-config" || mod.VersionId != "VERSION2" {
+		t.Errorf("got %s:%s, want the newest version in range cloth-config:VERSION2", mod.Project, mod.VersionId)
+	}
+	if received["GET /v1/mods/search"] != 0 {
+		t.Error("CurseForge was searched after a Modrinth match")
+	}
+
+	// Without a version in range, the newest version is used
+	dep.Versions = []string{">=13"}
+	if _, err := Resolve(dep, "fabric", "1.20.1"); err == nil {
+		t.Error("unserved version VERSION3 was resolved")
+	}
+	if received["GET /version/VERSION3"] != 1 {
+		t.Error("newest version was not used")
+	}
+}
+
+func TestResolveCurse(t *testing.T) {
+	received := serve(t, map[string]string{
+		"GET /search":                       `{"hits": []}`,
+		"GET /v1/mods/search":               `{"data": [{"id": 238222, "slug": "jei"}]}`,
+		"GET /v1/mods/238222/files":         `{"data": [{"id": 4567890}]}`,
+		"GET /v1/mods/238222/files/4567890": `{"data": ` + curseFileJSON + `}`,
+	})
+	dl, err := Resolve(Dependency{Id: "jei", Kind: DependRequired}, "forge", "1.20.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod := dl.(*CurseMod); mod.ModId != 238222 || mod.FileId != 4567890 {
+		t.Errorf("got file %d:%d, want 238222:4567890", mod.ModId, mod.FileId)
+	}
+	if received["GET /v1/mods/238222/files?gameVersion=1.20.1&modLoaderType=1&pageSize=1"] != 1 {
+		t.Errorf("files not filtered by loader and Minecraft version: %v", received)
+	}
+
+	serve(t, map[string]string{"GET /search": `{"hits": []}`, "GET /v1/mods/search": `{"data": []}`})
+	if _, err := Resolve(Dependency{Id: "jei", Kind: DependRequired}, "forge", "1.20.1"); err == nil {
+		t.Error("missing dependency resolved")
+	}
+}
@@ -0,0 +1,50 @@
+package mod
+
+import (
+	"github.com/faceless-saint/m3/lib/net"
+)
+
+// Sides a mod may be installed on.
+const (
+	SideBoth   = "both"
+	SideClient = "client"
+	SideServer = "server"
+)
+
+// Sided is the interface for mods installed on only one side.
+type Sided interface {
+	// Side returns the side the mod is installed on: SideClient,
+	// SideServer or SideBoth.
+	Side() string
+}
+
+// ValidSide returns true iff the side is a valid 'side' property value.
+func ValidSide(side string) bool {
+	return side == "" || side == SideBoth || side == SideClient || side == SideServer
+}
+
+// OnSide returns true iff the mod is installed on the given side. Every
+// mod is installed if the side is empty or SideBoth.
+func OnSide(dl net.Downloadable, side string) bool {
+	if side == "" || side == SideBoth {
+		return true
+	}
+	if sided, ok := dl.(Sided); ok {
+		return sided.Side() == SideBoth || sided.Side() == side
+	}
+	return true
+}
+
+// Filter removes the Items that are not installed on the given side. The
+// jars of removed mods are disabled by Clean.
+func (this *Directory) Filter(side string) {
+	items := net.Downloadables{}
+	for _, el := range this.Items {
+		if OnSide(el, side) {
+			items = append(items, el)
+		} else {
+			this.Inactive = append(this.Inactive, el)
+		}
+	}
+	this.Items = items
+}
@@ -0,0 +1,91 @@
+package mod
+
+import (
+	"github.com/faceless-saint/m3/lib/net"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Status values describe how the contents of a local mod directory
+// compare to a Directory spec. All entries are file names relative to
+// the mod directory.
+type Status struct {
+	// Valid files are defined in the spec and match their checksums.
+	Valid []string
+	// Missing files are defined in the spec but not present.
+	Missing []string
+	// Invalid files are defined in the spec but fail checksum validation.
+	Invalid []string
+	// Disabled files are defined in the spec but are currently disabled.
+	Disabled []string
+	// Extra files are jar files not defined in the spec.
+	Extra []string
+	// Ignored files are deliberately excluded from the spec.
+	Ignored []string
+}
+
+// Ok returns true iff every mod in the spec is present and valid.
+func (this *Status) Ok() bool {
+	return len(this.Missing) == 0 && len(this.Invalid) == 0 &&
+		len(this.Disabled) == 0
+}
+
+// Status compares the given mod directory against the Directory spec.
+// Unlike Clean, the filesystem is never modified.
+func (this *Directory) Status(dir string) (*Status, error) {
+	status := Status{}
+	present := make(map[string]os.FileInfo)
+	if _, err := os.Stat(dir); err == nil {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, el := range files {
+			present[el.Name()] = el
+		}
+	}
+	ignoreMap := make(map[string]*struct{}, len(this.Ignore))
+	for _, el := range this.Ignore {
+		ignoreMap[el] = new(struct{})
+	}
+	fileMap := make(map[string]net.Downloadable, len(this.Items))
+	for _, el := range this.Items {
+		fileMap[el.Filename()] = el
+	}
+	for _, mod := range this.Items {
+		name := mod.Filename()
+		if _, ok := present[name]; ok {
+			match, err := net.CheckFile(filepath.Join(dir, name),
+				mod.Checksum(), mod.Hash())
+			if err != nil {
+				return nil, err
+			}
+			if match {
+				status.Valid = append(status.Valid, name)
+			} else {
+				status.Invalid = append(status.Invalid, name)
+			}
+		} else if _, ok := present[name+".disabled"]; ok {
+			status.Disabled = append(status.Disabled, name)
+		} else {
+			status.Missing = append(status.Missing, name)
+		}
+	}
+	for name := range present {
+		if _, ok := ignoreMap[name]; ok {
+			status.Ignored = append(status.Ignored, name)
+		} else if _, ok := fileMap[name]; ok {
+			continue
+		} else if _, ok := fileMap[strings.TrimSuffix(name, ".disabled")]; ok {
+			continue
+		} else if filepath.Ext(name) == ".jar" {
+			status.Extra = append(status.Extra, name)
+		}
+	}
+	sort.Strings(status.Extra)
+	sort.Strings(status.Ignored)
+	return &status, nil
+}
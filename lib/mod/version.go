@@ -0,0 +1,236 @@
+package mod
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Version range syntaxes.
+const (
+	// SyntaxMaven ranges, used by Forge, have the form "[1.0,2.0)".
+	SyntaxMaven = "maven"
+	// SyntaxSemver ranges, used by Fabric, have the form ">=1.0 <2".
+	SyntaxSemver = "semver"
+)
+
+// versionPart values are the components of a parsed version: a number,
+// or a qualifier such as "beta".
+type versionPart struct {
+	num       int
+	qualifier string
+}
+
+// parseVersion splits the version into its components. Build metadata
+// following a "+" is dropped.
+func parseVersion(version string) []versionPart {
+	version = strings.ToLower(strings.SplitN(version, "+", 2)[0])
+	parts := []versionPart{}
+	token, digits := "", false
+	flush := func() {
+		if token == "" {
+			return
+		}
+		if digits {
+			n, _ := strconv.Atoi(token)
+			parts = append(parts, versionPart{num: n})
+		} else {
+			parts = append(parts, versionPart{qualifier: token})
+		}
+		token = ""
+	}
+	for _, c := range version {
+		switch {
+		case c == '.' || c == '-' || c == '_':
+			flush()
+		case unicode.IsDigit(c):
+			if !digits {
+				flush()
+			}
+			token, digits = token+string(c), true
+		default:
+			if digits {
+				flush()
+			}
+			token, digits = token+string(c), false
+		}
+	}
+	flush()
+	return parts
+}
+
+// CompareVersions returns -1, 0 or 1 if version 'a' is lower than, equal
+// to or higher than version 'b'. Numbers are compared numerically, and
+// qualifiers (such as "beta") mark versions before their release.
+// Missing numbers count as zero, so "1.0" equals "1.0.0".
+func CompareVersions(a, b string) int {
+	x, y := parseVersion(a), parseVersion(b)
+	for i := 0; i < len(x) || i < len(y); i++ {
+		var p, q *versionPart
+		if i < len(x) {
+			p = &x[i]
+		}
+		if i < len(y) {
+			q = &y[i]
+		}
+		if c := comparePart(p, q); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// comparePart compares two version components, either of which may be
+// missing (nil).
+func comparePart(p, q *versionPart) int {
+	switch {
+	case p == nil:
+		return -comparePart(q, p)
+	case q == nil:
+		// Qualifiers precede the release, while missing numbers are zero
+		if p.qualifier != "" {
+			return -1
+		}
+		return sign(p.num)
+	case p.qualifier == "" && q.qualifier == "":
+		return sign(p.num - q.num)
+	case p.qualifier == "":
+		return 1
+	case q.qualifier == "":
+		return -1
+	}
+	return strings.Compare(p.qualifier, q.qualifier)
+}
+
+// sign returns the sign of n.
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// MatchRange returns true iff the version is in the range, given in the
+// specified syntax. Empty and unparseable ranges match every version.
+func MatchRange(version, ranges, syntax string) bool {
+	if syntax == SyntaxSemver {
+		return matchSemver(version, ranges)
+	}
+	return matchMaven(version, ranges)
+}
+
+// matchMaven returns true iff the version is in any of the Maven ranges,
+// such as "[1.0,2.0)", "[1.0,)", "[1.0]" or "(,1.0],[1.2,)". A bare
+// version is only a recommendation, and matches every version.
+func matchMaven(version, ranges string) bool {
+	ranges = strings.TrimSpace(ranges)
+	if ranges == "" || ranges == "*" || !strings.ContainsAny(ranges[:1], "[(") {
+		return true
+	}
+	for ranges != "" {
+		end := strings.IndexAny(ranges, "])")
+		if end < 0 {
+			return true
+		}
+		bounds := strings.Split(ranges[1:end], ",")
+		low, high := strings.TrimSpace(bounds[0]), ""
+		if len(bounds) > 1 {
+			high = strings.TrimSpace(bounds[1])
+		} else {
+			// "[1.0]" matches exactly
+			high = low
+		}
+		ok := true
+		if low != "" {
+			c := CompareVersions(version, low)
+			ok = c > 0 || (c == 0 && ranges[0] == '[')
+		}
+		if high != "" {
+			c := CompareVersions(version, high)
+			ok = ok && (c < 0 || (c == 0 && ranges[end] == ']'))
+		}
+		if ok {
+			return true
+		}
+		ranges = strings.TrimLeft(ranges[end+1:], ", ")
+	}
+	return false
+}
+
+// matchSemver returns true iff the version matches every space-separated
+// predicate of the range, such as ">=1.2 <2", "~1.2", "^1.2.3", "1.20.x"
+// or "*".
+func matchSemver(version, ranges string) bool {
+	for _, el := range strings.Fields(ranges) {
+		op := ""
+		for _, prefix := range []string{">=", "<=", ">", "<", "=", "~", "^"} {
+			if strings.HasPrefix(el, prefix) {
+				op = prefix
+				break
+			}
+		}
+		want := strings.TrimPrefix(el, op)
+		if want == "" || want == "*" || want == "x" || want == "X" {
+			continue
+		}
+		if op == "" || op == "=" {
+			if !matchWildcard(version, want) {
+				return false
+			}
+			continue
+		}
+		want = strings.TrimRight(strings.NewReplacer("x", "0", "X", "0", "*", "0").Replace(want), ".")
+		c := CompareVersions(version, want)
+		var ok bool
+		switch op {
+		case ">=":
+			ok = c >= 0
+		case "<=":
+			ok = c <= 0
+		case ">":
+			ok = c > 0
+		case "<":
+			ok = c < 0
+		case "~", "^":
+			ok = c >= 0 && CompareVersions(version, nextVersion(want, op == "^")) < 0
+		default:
+			ok = true
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchWildcard returns true iff the version equals the wanted version,
+// whose "x" or "*" components match any value.
+func matchWildcard(version, want string) bool {
+	if !strings.ContainsAny(want, "xX*") {
+		return CompareVersions(version, want) == 0
+	}
+	have := strings.Split(strings.SplitN(version, "-", 2)[0], ".")
+	for i, el := range strings.Split(want, ".") {
+		if el == "x" || el == "X" || el == "*" {
+			return true
+		} else if i >= len(have) || el != have[i] {
+			return false
+		}
+	}
+	return len(have) == len(strings.Split(want, "."))
+}
+
+// nextVersion returns the lowest version excluded by a "~" or, if 'major'
+// is true, a "^" predicate on the version: the next minor or major version.
+func nextVersion(version string, major bool) string {
+	split := strings.Split(strings.SplitN(version, "-", 2)[0], ".")
+	i := 1
+	if major || len(split) < 2 {
+		i = 0
+	}
+	n, _ := strconv.Atoi(split[i])
+	return strings.Join(append(split[:i], strconv.Itoa(n+1)), ".")
+}
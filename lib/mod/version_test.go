@@ -0,0 +1,80 @@
+package mod
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		A, B string
+		Want int
+	}{
+		{"1.0", "1.0.0", 0},
+		{"1.2", "1.10", -1},
+		{"1.20.1", "1.20", 1},
+		{"1.0-beta", "1.0", -1},
+		{"1.0-alpha", "1.0-beta", -1},
+		{"1.0+build.5", "1.0", 0},
+		{"47.1.0", "47.0.35", 1},
+		{"0.5.3", "0.5.3-rc1", 1},
+	} {
+		if got := CompareVersions(test.A, test.B); got != test.Want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", test.A, test.B, got, test.Want)
+		}
+	}
+}
+
+func TestMatchRange(t *testing.T) {
+	for _, test := range []struct {
+		Version, Range, Syntax string
+		Want                   bool
+	}{
+		{"1.20.1", "[1.20.1,1.20.2)", SyntaxMaven, true},
+		{"1.20.2", "[1.20.1,1.20.2)", SyntaxMaven, false},
+		{"1.20", "(1.20,1.21]", SyntaxMaven, false},
+		{"1.21", "(1.20,1.21]", SyntaxMaven, true},
+		{"47.2.0", "[47,)", SyntaxMaven, true},
+		{"46.0.1", "[47,)", SyntaxMaven, false},
+		{"0.6.10", "[0.6.10]", SyntaxMaven, true},
+		{"0.6.11", "[0.6.10]", SyntaxMaven, false},
+		{"1.1", "(,1.0],[1.2,)", SyntaxMaven, false},
+		{"1.3", "(,1.0],[1.2,)", SyntaxMaven, true},
+		// Bare versions are recommendations
+		{"0.1", "1.0", SyntaxMaven, true},
+		{"0.1", "*", SyntaxMaven, true},
+		{"0.1", "", SyntaxMaven, true},
+		{"0.14.21", ">=0.14.0", SyntaxSemver, true},
+		{"0.13.3", ">=0.14.0", SyntaxSemver, false},
+		{"1.20.1", ">=1.20 <1.21", SyntaxSemver, true},
+		{"1.21", ">=1.20 <1.21", SyntaxSemver, false},
+		{"1.20.4", "1.20.x", SyntaxSemver, true},
+		{"1.19.4", "1.20.x", SyntaxSemver, false},
+		{"1.20", "1.20.x", SyntaxSemver, true},
+		{"1.20.1", "~1.20", SyntaxSemver, true},
+		{"1.21", "~1.20", SyntaxSemver, false},
+		{"11.9", "^11.1", SyntaxSemver, true},
+		{"12.0", "^11.1", SyntaxSemver, false},
+		{"1.20.1", "=1.20.1", SyntaxSemver, true},
+		{"1.20.2", "1.20.1", SyntaxSemver, false},
+		{"0.5.3", "*", SyntaxSemver, true},
+	} {
+		if got := MatchRange(test.Version, test.Range, test.Syntax); got != test.Want {
+			t.Errorf("MatchRange(%q, %q, %s) = %v, want %v", test.Version, test.Range, test.Syntax, got, test.Want)
+		}
+	}
+}
+
+func TestDependencyMatches(t *testing.T) {
+	dep := Dependency{Id: "minecraft", Versions: []string{"1.20", "1.20.1"}, Syntax: SyntaxSemver}
+	for version, want := range map[string]bool{"1.20": true, "1.20.1": true, "1.20.2": false, "": true} {
+		if got := dep.Matches(version); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", version, got, want)
+		}
+	}
+	if dep.Range() != "1.20 || 1.20.1" {
+		t.Errorf("got range %q", dep.Range())
+	}
+	if any := (Dependency{Id: "fabric-api"}); !any.Matches("0.1") || any.Range() != "*" {
+		t.Error("dependency without ranges does not match every version")
+	}
+}
@@ -0,0 +1,81 @@
+package net
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// UserAgent is the User-Agent header sent with all requests.
+const UserAgent = "m3"
+
+// Get sends a GET request to the given URL with the given extra headers,
+// and returns the response body. Responses with a non 200 status code
+// are returned as errors.
+func Get(url string, header http.Header) ([]byte, error) {
+	return Do("GET", url, header, nil)
+}
+
+// Do sends a request with the given method, extra headers and body (if
+// not nil), and returns the response body as Get does.
+func Do(method, url string, header http.Header, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: request to %s failed: %s", url, resp.Status)
+	}
+	return data, nil
+}
+
+// GetJSON sends a GET request to the given URL with the given extra
+// headers, and decodes the JSON response into 'v'.
+func GetJSON(url string, header http.Header, v interface{}) error {
+	return DoJSON("GET", url, header, nil, v)
+}
+
+// PostJSON sends a POST request with 'body' encoded as JSON to the given
+// URL, and decodes the JSON response into 'v' as GetJSON does.
+func PostJSON(url string, header http.Header, body, v interface{}) error {
+	return DoJSON("POST", url, header, body, v)
+}
+
+// DoJSON sends a request with the given method, extra headers and JSON
+// encoded body (if not nil), and decodes the JSON response into 'v'.
+func DoJSON(method, url string, header http.Header, body, v interface{}) error {
+	h := http.Header{"Accept": {"application/json"}}
+	for key, values := range header {
+		h[key] = values
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+		h.Set("Content-Type", "application/json")
+	}
+	data, err := Do(method, url, h, reader)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
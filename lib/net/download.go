@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"github.com/cavaliercoder/grab"
 	"hash"
+	"net/http"
 	"os"
 	"path/filepath"
 )
@@ -20,29 +21,55 @@ type Downloadable interface {
 	Hash() hash.Hash
 }
 
+// HeaderDownloadable is the interface for Downloadables that require
+// extra request headers, such as authorization, to be downloaded.
+type HeaderDownloadable interface {
+	Downloadable
+	// Header returns the extra headers for the download request.
+	Header() http.Header
+}
+
+// SizedDownloadable is the interface for Downloadables with a published
+// file size, which downloads are checked against.
+type SizedDownloadable interface {
+	Downloadable
+	// FileSize returns the size of the file in bytes, or 0 if unknown.
+	FileSize() int64
+}
+
 // Downloadables values are lists of objects implementing Downloadable.
 type Downloadables []Downloadable
 
 // GetFiles downloads all files in the Downloadables list and saves them
 // to the target directory, using at most 'num' simultaneous downloads.
 // Returns a channel emitting Response objects as they become available.
+// Local files are copied before any downloads start, and are not
+// included in the responses.
 func (this *Downloadables) GetFiles(dir string, num int) (<-chan *grab.Response, int, error) {
+	for _, el := range this.CopyFiles(dir) {
+		if el.Error != nil {
+			return nil, 0, el.Error
+		}
+	}
 	reqs, err := this.GetFilesDeferred(dir)
 	if err != nil {
 		return nil, 0, err
 	}
 	// Start downloads and return the response channel
 	client := grab.NewClient()
-	client.UserAgent = "m3"
+	client.UserAgent = UserAgent
 	return client.DoBatch(num, reqs...), len(reqs), nil
 }
 
-// GetFilesDeferred creates a download request for every file in the
-// Downloadables list that isn't found in the target directory.
+// GetFilesDeferred creates a download request for every remote file in
+// the Downloadables list that isn't found in the target directory.
 func (this *Downloadables) GetFilesDeferred(dir string) ([]*grab.Request, error) {
 	// Prepare each download request
 	reqs := []*grab.Request{}
 	for _, dl := range *this {
+		if IsLocal(dl) {
+			continue
+		}
 		destination := filepath.Join(dir, dl.Filename())
 		if _, err := os.Stat(destination); err != nil {
 			req, err := GetFileDeferred(dl, destination)
@@ -67,7 +94,7 @@ func GetFile(dl Downloadable, file string) (*grab.Response, error) {
 	}
 	// Execute the request with the default client.
 	client := grab.NewClient()
-	client.UserAgent = "m3"
+	client.UserAgent = UserAgent
 	return client.Do(req)
 }
 
@@ -95,6 +122,12 @@ func GetFileDeferred(dl Downloadable, file string) (*grab.Request, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Add any extra request headers
+	if hdl, ok := dl.(HeaderDownloadable); ok {
+		for key, values := range hdl.Header() {
+			req.HTTPRequest.Header[key] = values
+		}
+	}
 	// Attempt to add the file's checksum for verification.
 	if len(dl.Checksum()) != 0 {
 		if checksum, err := hex.DecodeString(dl.Checksum()); err == nil {
@@ -102,6 +135,10 @@ func GetFileDeferred(dl Downloadable, file string) (*grab.Request, error) {
 			req.Hash = dl.Hash()
 		}
 	}
+	// Add the file's size for verification.
+	if sdl, ok := dl.(SizedDownloadable); ok && sdl.FileSize() > 0 {
+		req.Size = uint64(sdl.FileSize())
+	}
 	// Make sure the target directory exists.
 	os.MkdirAll(filepath.Dir(file), 0755)
 	req.RemoveOnError = true
@@ -7,13 +7,15 @@ import (
 	"crypto/sha512"
 	"fmt"
 	"hash"
+	"io"
 	"io/ioutil"
 	"math"
 	"os"
 )
 
 // NewHash returns a new hash implementing the chosen algorithm.
-// Supported algorithms: "sha512", "sha256", "sha1", "md5", "git"
+// Supported algorithms: "sha512", "sha256", "sha1", "md5", "git",
+// "murmur2"
 func NewHash(h string) (hash.Hash, error) {
 	switch h {
 	case "sha512":
@@ -26,6 +28,8 @@ func NewHash(h string) (hash.Hash, error) {
 		return md5.New(), nil
 	case "git":
 		return &GitHash{}, nil
+	case "murmur2":
+		return &Murmur2Hash{}, nil
 	default:
 		return nil, fmt.Errorf("error: unsupported hash type %s", h)
 	}
@@ -36,6 +40,8 @@ func DefaultHash() hash.Hash { return sha256.New() }
 
 // ByteChecksum returns the hex-encoded checksum of the data bytes.
 func ByteChecksum(data []byte, h hash.Hash) string {
+	// The hash may have been left in use by a download
+	h.Reset()
 	h.Write(data)
 	checksum := fmt.Sprintf("%x", h.Sum([]byte{}))
 	h.Reset()
@@ -56,6 +62,30 @@ func FileChecksum(file string, h hash.Hash) (string, error) {
 	return ByteChecksum(data, h), nil
 }
 
+// FileChecksums returns the hex-encoded checksums of the file for each of
+// the given hashes, reading the file only once.
+func FileChecksums(file string, h ...hash.Hash) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	writers := make([]io.Writer, len(h))
+	for i, el := range h {
+		el.Reset()
+		writers[i] = el
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return nil, err
+	}
+	sums := make([]string, len(h))
+	for i, el := range h {
+		sums[i] = fmt.Sprintf("%x", el.Sum([]byte{}))
+		el.Reset()
+	}
+	return sums, nil
+}
+
 // Digest returns a short digest consisting of the first 'n' characters
 // of the given string's checksum. The library default hash is used.
 func Digest(str string, n int) string {
@@ -87,6 +117,17 @@ func VerifyFile(file, checksum string, h hash.Hash) error {
 	return nil
 }
 
+// CheckFile compares the file against a reference checksum without
+// modifying it, returning true iff they match or the reference checksum
+// is empty.
+func CheckFile(file, checksum string, h hash.Hash) (bool, error) {
+	sum, err := FileChecksum(file, h)
+	if err != nil {
+		return false, err
+	}
+	return len(checksum) == 0 || sum == checksum, nil
+}
+
 // ByteCountToString returns a human readable representation of the
 // given raw byte count using SI units to compactly display the size.
 func ByteCountToString(bytes uint64) string {
@@ -121,3 +162,57 @@ func (this *GitHash) Sum(data []byte) []byte {
 func (this *GitHash) Reset()         { this.data = []byte{}; this.size = 0 }
 func (this *GitHash) Size() int      { return this.size }
 func (this *GitHash) BlockSize() int { return 64 }
+
+// Murmur2Hash is an implementation of hash.Hash for CurseForge file
+// fingerprints: the 32-bit MurmurHash2 (seed 1) of the file contents with
+// all whitespace bytes removed. It corresponds to the NewHash function's
+// "murmur2" hash type. Sums are big-endian.
+type Murmur2Hash struct {
+	data []byte
+}
+
+func (this *Murmur2Hash) Write(data []byte) (int, error) {
+	for _, b := range data {
+		if b != 9 && b != 10 && b != 13 && b != 32 {
+			this.data = append(this.data, b)
+		}
+	}
+	return len(data), nil
+}
+func (this *Murmur2Hash) Sum(data []byte) []byte {
+	sum := Murmur2(this.data, 1)
+	return append(data, byte(sum>>24), byte(sum>>16), byte(sum>>8), byte(sum))
+}
+func (this *Murmur2Hash) Reset()         { this.data = []byte{} }
+func (this *Murmur2Hash) Size() int      { return 4 }
+func (this *Murmur2Hash) BlockSize() int { return 4 }
+
+// Murmur2 returns the 32-bit MurmurHash2 of the data with the given seed.
+func Murmur2(data []byte, seed uint32) uint32 {
+	const m = 0x5bd1e995
+	h := seed ^ uint32(len(data))
+	for ; len(data) >= 4; data = data[4:] {
+		k := uint32(data[0]) | uint32(data[1])<<8 |
+			uint32(data[2])<<16 | uint32(data[3])<<24
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
@@ -0,0 +1,104 @@
+package net
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalDownloadable is the interface for Downloadables sourced from the
+// local filesystem rather than a remote URL.
+type LocalDownloadable interface {
+	Downloadable
+	// Source returns the path of the local source file, or an empty
+	// string if the file is not available locally.
+	Source() string
+}
+
+// CopyResult values record the outcome of copying a local file.
+type CopyResult struct {
+	Filename string
+	Error    error
+}
+
+// IsLocal returns true iff the Downloadable is sourced from a local file.
+func IsLocal(dl Downloadable) bool {
+	ldl, ok := dl.(LocalDownloadable)
+	return ok && ldl.Source() != ""
+}
+
+// Split separates the Downloadables list into remote and local files.
+func (this *Downloadables) Split() (Downloadables, Downloadables) {
+	remote, local := Downloadables{}, Downloadables{}
+	for _, dl := range *this {
+		if IsLocal(dl) {
+			local = append(local, dl)
+		} else {
+			remote = append(remote, dl)
+		}
+	}
+	return remote, local
+}
+
+// CopyFiles copies every local file in the Downloadables list that isn't
+// found in the target directory, and returns the result of each copy.
+func (this *Downloadables) CopyFiles(dir string) []*CopyResult {
+	results := []*CopyResult{}
+	for _, dl := range *this {
+		if !IsLocal(dl) {
+			continue
+		}
+		destination := filepath.Join(dir, dl.Filename())
+		if _, err := os.Stat(destination); err != nil {
+			err := CopyFile(dl.(LocalDownloadable), destination)
+			results = append(results, &CopyResult{destination, err})
+		}
+	}
+	return results
+}
+
+// CopyFile hardlinks the source of the given LocalDownloadable to the
+// target file, falling back to a full copy if linking is not possible.
+// The copied file is verified against the reference checksum and
+// removed if it does not match.
+func CopyFile(dl LocalDownloadable, file string) error {
+	if file == "" {
+		file = dl.Filename()
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(dl.Source(), file); err != nil {
+		if err := copyFile(dl.Source(), file); err != nil {
+			os.Remove(file)
+			return err
+		}
+	}
+	match, err := CheckFile(file, dl.Checksum(), dl.Hash())
+	if err != nil {
+		return err
+	} else if !match {
+		os.Remove(file)
+		return fmt.Errorf("error: checksum mismatch for %s", dl.Source())
+	}
+	return nil
+}
+
+// copyFile copies the contents of the source file to the target file.
+func copyFile(source, file string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,83 @@
+package net
+
+import (
+	"crypto/sha256"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// localFile is a LocalDownloadable for tests.
+type localFile struct {
+	name, source, checksum string
+}
+
+func (this *localFile) Url() string      { return "" }
+func (this *localFile) Filename() string { return this.name }
+func (this *localFile) Checksum() string { return this.checksum }
+func (this *localFile) Hash() hash.Hash  { return sha256.New() }
+func (this *localFile) Source() string   { return this.source }
+
+// remoteFile is a Downloadable for tests.
+type remoteFile struct {
+	name string
+}
+
+func (this *remoteFile) Url() string      { return "https://example.com/" + this.name }
+func (this *remoteFile) Filename() string { return this.name }
+func (this *remoteFile) Checksum() string { return "" }
+func (this *remoteFile) Hash() hash.Hash  { return sha256.New() }
+
+func TestSplit(t *testing.T) {
+	dls := Downloadables{&remoteFile{"a.jar"}, &localFile{"b.jar", "src/b.jar", ""},
+		&localFile{"c.jar", "", ""}}
+	remote, local := dls.Split()
+	if len(remote) != 2 || remote[0] != dls[0] || remote[1] != dls[2] {
+		t.Errorf("got remote files %v, want files without a local source", remote)
+	}
+	if len(local) != 1 || local[0] != dls[1] {
+		t.Errorf("got local files %v", local)
+	}
+}
+
+func TestCopyFiles(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "src", "b.jar")
+	os.MkdirAll(filepath.Dir(source), 0755)
+	if err := ioutil.WriteFile(source, []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "mods")
+	os.MkdirAll(target, 0755)
+	if err := ioutil.WriteFile(filepath.Join(target, "c.jar"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := StringChecksum("b", sha256.New())
+	dls := Downloadables{&remoteFile{"a.jar"}, &localFile{"b.jar", source, sum},
+		&localFile{"c.jar", source, sum}, &localFile{"d.jar", source, "bad"}}
+
+	// Existing files are not replaced, and mismatched copies are removed
+	results := dls.CopyFiles(target)
+	if len(results) != 2 {
+		t.Fatalf("got %d copies, want 2", len(results))
+	}
+	if results[0].Error != nil || results[0].Filename != filepath.Join(target, "b.jar") {
+		t.Errorf("got result %+v", results[0])
+	}
+	if results[1].Error == nil {
+		t.Error("copy with mismatched checksum accepted")
+	}
+	for name, want := range map[string]string{"b.jar": "b", "c.jar": "old"} {
+		if data, err := ioutil.ReadFile(filepath.Join(target, name)); err != nil || string(data) != want {
+			t.Errorf("%s: got %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target, "d.jar")); !os.IsNotExist(err) {
+		t.Error("mismatched copy was kept")
+	}
+	if err := CopyFile(&localFile{"e.jar", filepath.Join(dir, "missing.jar"), ""}, filepath.Join(target, "e.jar")); err == nil {
+		t.Error("missing source copied")
+	}
+}
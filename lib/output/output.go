@@ -3,7 +3,9 @@ package output
 import (
 	"fmt"
 	"github.com/cavaliercoder/grab"
+	"github.com/faceless-saint/m3/lib/net"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -15,6 +17,11 @@ type DownloadTracker struct {
 	Interval  time.Duration
 	Count     int
 	Total     int
+	// Dir is the download directory, which is omitted from logged file
+	// names. Defaults to Name.
+	Dir string
+	// Local is the list of local files copied alongside the downloads.
+	Local []*net.CopyResult
 }
 
 type DirectDownloadTracker struct {
@@ -26,13 +33,26 @@ type DirectDownloadTracker struct {
 // Log records the download progress without any ANSI terminal handling
 // or other fancy features, ideal for output to files or DOS terminals.
 func (this *DownloadTracker) Log() {
-	if this.Count == 0 {
+	if this.Count == 0 && len(this.Local) == 0 {
 		fmt.Printf("%d %s found. Nothing to download.\n", this.Total, this.Name)
 		return
 	}
 
 	// Print header
-	fmt.Printf("Downloading %d %s... (%d found locally)\n", this.Count, this.Name, this.Total-this.Count)
+	fmt.Printf("Downloading %d %s... (%d copied, %d found locally)\n", this.Count,
+		this.Name, len(this.Local), this.Total-this.Count-len(this.Local))
+
+	// Log copied local files
+	for _, el := range this.Local {
+		if el.Error != nil {
+			fmt.Fprintf(os.Stderr, "\t%s (local) - err: %v\n", this.trim(el.Filename), el.Error)
+		} else {
+			fmt.Printf("\t%s (local)\n", this.trim(el.Filename))
+		}
+	}
+	if this.Count == 0 {
+		return
+	}
 
 	// Set up timer for download tracker
 	t := time.NewTicker(this.Interval * time.Millisecond)
@@ -53,9 +73,9 @@ func (this *DownloadTracker) Log() {
 				if resp != nil && resp.IsComplete() {
 					completed++
 					if resp.Error != nil {
-						fmt.Fprintf(os.Stderr, "\t%s - err: %v\n", strings.Replace(resp.Filename, this.Name+"/", "", 1), resp.Error)
+						fmt.Fprintf(os.Stderr, "\t%s - err: %v\n", this.filename(resp), resp.Error)
 					} else {
-						fmt.Printf("\t%s\n", strings.Replace(resp.Filename, this.Name+"/", "", 1))
+						fmt.Printf("\t%s\n", this.filename(resp))
 					}
 					this.Responses = append(this.Responses, resp)
 					responses[i] = nil
@@ -65,6 +85,42 @@ func (this *DownloadTracker) Log() {
 	}
 }
 
+// filename returns the display name of the response's file.
+func (this *DownloadTracker) filename(resp *grab.Response) string {
+	return this.trim(resp.Filename)
+}
+
+// trim returns the display name of the given file.
+func (this *DownloadTracker) trim(file string) string {
+	dir := this.Dir
+	if dir == "" {
+		dir = this.Name
+	}
+	return strings.Replace(file, dir+string(filepath.Separator), "", 1)
+}
+
+// Failed returns the logged responses that completed with an error.
+func (this *DownloadTracker) Failed() []*grab.Response {
+	failed := []*grab.Response{}
+	for _, resp := range this.Responses {
+		if resp.Error != nil {
+			failed = append(failed, resp)
+		}
+	}
+	return failed
+}
+
+// FailedLocal returns the local files that could not be copied.
+func (this *DownloadTracker) FailedLocal() []*net.CopyResult {
+	failed := []*net.CopyResult{}
+	for _, el := range this.Local {
+		if el.Error != nil {
+			failed = append(failed, el)
+		}
+	}
+	return failed
+}
+
 /*
 func trackDownloadStatus(
         ch <-chan *grab.Response,
@@ -0,0 +1,227 @@
+package pack
+
+import (
+	"archive/zip"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/spec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// curseManifest values act as JSON import and export containers for the
+// manifest.json files of CurseForge modpacks.
+type curseManifest struct {
+	Minecraft struct {
+		Version    string        `json:"version"`
+		ModLoaders []curseLoader `json:"modLoaders"`
+	} `json:"minecraft"`
+	ManifestType    string      `json:"manifestType"`
+	ManifestVersion int         `json:"manifestVersion"`
+	Name            string      `json:"name"`
+	Version         string      `json:"version"`
+	Author          string      `json:"author"`
+	Files           []curseFile `json:"files"`
+	Overrides       string      `json:"overrides"`
+}
+
+// curseLoader values are the mod loaders of CurseForge manifests, with IDs
+// of the form "<type>-<version>".
+type curseLoader struct {
+	Id      string `json:"id"`
+	Primary bool   `json:"primary"`
+}
+
+// curseFile values are the CurseForge files listed in manifests.
+type curseFile struct {
+	ProjectID int  `json:"projectID"`
+	FileID    int  `json:"fileID"`
+	Required  bool `json:"required"`
+}
+
+// ImportCurseForge returns a new Raw spec of the given CurseForge modpack
+// zip, for a spec in the directory 'dir'. Files become CurseForge mods,
+// with files that are not required becoming optional mods. The overrides
+// are extracted into the OverridesDir of 'dir': their configs become the
+// spec's config overlay and their jars become local mods. Mods are named
+// by their CurseForge slug if an API key is set.
+func ImportCurseForge(file, dir string) (*spec.Raw, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var m curseManifest
+	if err := readJSON(&r.Reader, "manifest.json", &m); err != nil {
+		return nil, err
+	}
+	if m.ManifestType != "minecraftModpack" {
+		return nil, fmt.Errorf("error: unsupported CurseForge manifest type %q", m.ManifestType)
+	}
+
+	this := spec.Raw{SpecVersion: spec.SpecVersion, Name: m.Name, Version: m.Version,
+		Mods: mod.RawDirectory{Items: []mod.Raw{}}}
+	for _, el := range m.Minecraft.ModLoaders {
+		if el.Primary || len(m.Minecraft.ModLoaders) == 1 {
+			split := strings.SplitN(el.Id, "-", 2)
+			if len(split) != 2 {
+				return nil, fmt.Errorf("error: invalid CurseForge mod loader %q", el.Id)
+			}
+			if this.Loader, err = newLoader(split[0], split[1], m.Minecraft.Version); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Name the mods by slug if possible
+	slugs := map[int]string{}
+	if mod.CurseApiKey != "" && len(m.Files) > 0 {
+		ids := []int{}
+		for _, el := range m.Files {
+			ids = append(ids, el.ProjectID)
+		}
+		if slugs, err = mod.CurseSlugs(ids); err != nil {
+			return nil, err
+		}
+	}
+	names := map[string]*struct{}{}
+	for _, el := range m.Files {
+		name := slugs[el.ProjectID]
+		if name == "" {
+			name = fmt.Sprintf("curse-%d", el.ProjectID)
+		}
+		this.Mods.Items = append(this.Mods.Items, mod.Raw{Name: uniqueName(name, names),
+			Curse: fmt.Sprintf("%d:%d", el.ProjectID, el.FileID), Optional: !el.Required})
+	}
+
+	// Extract the overrides next to the spec
+	if m.Overrides == "" {
+		m.Overrides = "overrides"
+	}
+	overrides := filepath.Join(dir, OverridesDir)
+	extracted, err := extract(&r.Reader, strings.Trim(m.Overrides, "/"), overrides)
+	if err != nil {
+		return nil, err
+	}
+	skipped := map[string]*struct{}{}
+	for _, el := range extracted {
+		switch top := strings.SplitN(el, "/", 2)[0]; top {
+		case spec.ConfigDir:
+			this.Config.Overlay = OverridesDir + "/" + spec.ConfigDir
+		case "mods":
+		default:
+			skipped[top] = new(struct{})
+		}
+	}
+	local, err := localMods(filepath.Join(overrides, "mods"), dir, "", names)
+	if err != nil {
+		return nil, err
+	}
+	this.Mods.Items = append(this.Mods.Items, local...)
+	printSkipped(skipped, overrides)
+	fmt.Fprintf(Log, "Imported %d CurseForge mods and %d bundled mods.\n", len(m.Files), len(local))
+	return &this, nil
+}
+
+// ExportCurseForge writes the Spec as a CurseForge modpack zip to the given
+// file. CurseForge mods are referenced by the manifest, and are only
+// required if they are installed: the optional mods of unselected features
+// are listed as not required. Other mods, and the configs, are bundled into
+// the overrides from their local copies. Only the client's mods are
+// exported.
+func ExportCurseForge(s *spec.Spec, file string, src *Sources) error {
+	if s.Loader == nil {
+		return fmt.Errorf("error: exporting a CurseForge modpack requires a loader")
+	}
+	version, err := loaderVersion(s.Loader)
+	if err != nil {
+		return err
+	}
+	m := curseManifest{ManifestType: "minecraftModpack", ManifestVersion: 1,
+		Name: s.Name, Version: s.Version, Files: []curseFile{}, Overrides: "overrides"}
+	if m.Name == "" {
+		m.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	m.Minecraft.Version = s.Loader.Minecraft()
+	m.Minecraft.ModLoaders = []curseLoader{{s.Loader.Name() + "-" + version, true}}
+
+	// Locate the bundled files before writing anything
+	bundled := map[string]string{}
+	for _, el := range s.Mods.Items {
+		if !mod.OnSide(el, mod.SideClient) {
+			continue
+		}
+		if curse, ok := el.(*mod.CurseMod); ok && curse.ModId != 0 {
+			m.Files = append(m.Files, curseFile{curse.ModId, curse.FileId, true})
+			continue
+		}
+		local, err := find(src.Mods, el)
+		if err != nil {
+			return err
+		}
+		bundled["overrides/mods/"+el.Filename()] = local
+	}
+	for _, el := range s.Mods.Inactive {
+		curse, ok := el.(*mod.CurseMod)
+		if ok && curse.ModId != 0 && mod.OnSide(el, mod.SideClient) {
+			m.Files = append(m.Files, curseFile{curse.ModId, curse.FileId, false})
+		}
+	}
+	for _, el := range s.Config.Items {
+		if s.Config.FileSide(el.Filename()) == mod.SideServer {
+			continue
+		}
+		local, err := find(src.Configs, el)
+		if err != nil {
+			return err
+		}
+		bundled["overrides/config/"+el.Filename()] = local
+	}
+
+	w, err := create(file)
+	if err != nil {
+		return err
+	}
+	if err := w.addJSON("manifest.json", &m); err != nil {
+		w.abort()
+		return err
+	}
+	if err := addBundled(w, bundled); err != nil {
+		w.abort()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(Log, "Exported %d CurseForge mods and %d bundled files.\n", len(m.Files), len(bundled))
+	return nil
+}
+
+// addBundled copies the local files into the archive, by archive path.
+func addBundled(w *writer, bundled map[string]string) error {
+	names := []string{}
+	for name := range bundled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := w.addFile(name, bundled[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printSkipped warns about the extracted files that are not installed.
+func printSkipped(skipped map[string]*struct{}, dir string) {
+	if len(skipped) == 0 {
+		return
+	}
+	names := []string{}
+	for name := range skipped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(Log, "Not installed by m3: %s (extracted to %s)\n", strings.Join(names, ", "), dir)
+}
@@ -0,0 +1,130 @@
+package pack
+
+import (
+	"github.com/faceless-saint/m3/lib/loader"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/spec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// curseFileJSON returns the CurseForge API response for the file of the mod.
+func curseFileJSON(modId, fileId, name string) string {
+	return `{"data": {"id": ` + fileId + `, "modId": ` + modId + `, "fileName": "` + name + `",
+		"fileLength": 4, "downloadUrl": "https://edge.forgecdn.net/files/` + name + `",
+		"hashes": [{"value": "0ab1c2", "algo": 1}]}}`
+}
+
+func TestCurseForgeRoundTrip(t *testing.T) {
+	quiet(t)
+	serve(t, map[string]string{
+		"GET /v1/mods/238222/files/4567890": curseFileJSON("238222", "4567890", "jei.jar"),
+		"GET /v1/mods/419699/files/4712000": curseFileJSON("419699", "4712000", "architectury.jar"),
+		"GET /v1/mods/455508/files/4800000": curseFileJSON("455508", "4800000", "oculus.jar"),
+		"POST /v1/mods": `{"data": [{"id": 238222, "slug": "jei"}, {"id": 419699, "slug": "architectury-api"},
+			{"id": 455508, "slug": "oculus"}]}`,
+	})
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"modpack.json": `{"name": "Pack", "version": "1.0",
+			"loader": {"type": "forge", "version": "1.20.1-47.2.0"},
+			"config": {"overlay": "cfg", "sides": {"server.toml": "server"}},
+			"mods": {"items": [
+				{"name": "jei", "curse": "238222:4567890"},
+				{"name": "architectury", "curse": "419699:4712000", "side": "server"},
+				{"name": "shaders", "curse": "455508:4800000", "optional": true},
+				{"name": "extra", "path": "local/extra.jar"}
+			]}}`,
+		"cfg/jei.toml":    "jei",
+		"cfg/server.toml": "server",
+	})
+	zipFile(t, filepath.Join(dir, "local", "extra.jar"), map[string]string{
+		"META-INF/mods.toml": "[[mods]]\nmodId = \"extramod\"\nversion = \"1.0\"\n",
+	})
+	s, err := spec.FromFile(filepath.Join(dir, "modpack.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Config.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	s.Mods.Select(mod.Selection{"shaders": false})
+	file := filepath.Join(dir, "pack.zip")
+	if err := ExportCurseForge(s, file, &Sources{}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	raw, err := ImportCurseForge(file, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Name != "Pack" || raw.Version != "1.0" {
+		t.Errorf("got pack %s %s, want Pack 1.0", raw.Name, raw.Version)
+	}
+	if raw.Loader != (loader.Raw{Type: "forge", Version: "1.20.1-47.2.0"}) {
+		t.Errorf("got loader %+v", raw.Loader)
+	}
+	got := []string{}
+	for _, el := range raw.Mods.Items {
+		ref := el.Curse
+		if ref == "" {
+			ref = el.Path
+		}
+		if el.Optional {
+			ref += " optional"
+		}
+		got = append(got, el.Name+" "+ref)
+	}
+	// Server mods are left out, and unselected features are not required
+	want := "jei 238222:4567890|oculus 455508:4800000 optional|extramod overrides/mods/" +
+		s.Mods.Items[2].Filename()
+	if strings.Join(got, "|") != want {
+		t.Errorf("got mods %q, want %q", strings.Join(got, "|"), want)
+	}
+	if raw.Config.Overlay != "overrides/config" {
+		t.Errorf("got config overlay %q", raw.Config.Overlay)
+	}
+	if data := readFile(filepath.Join(out, "overrides", "config", "jei.toml")); data != "jei" {
+		t.Errorf("got config %q", data)
+	}
+	if data := readFile(filepath.Join(out, "overrides", "config", "server.toml")); !strings.Contains(data, "no such file") {
+		t.Error("server config was exported")
+	}
+}
+
+func TestImportCurseForge(t *testing.T) {
+	quiet(t)
+	serve(t, nil)
+	mod.CurseApiKey = ""
+	dir := t.TempDir()
+	file := filepath.Join(dir, "pack.zip")
+	zipFile(t, file, map[string]string{
+		"manifest.json": `{"minecraft": {"version": "1.20.1", "modLoaders": [
+				{"id": "forge-47.1.0", "primary": false}, {"id": "neoforge-47.1.82", "primary": true}]},
+			"manifestType": "minecraftModpack", "manifestVersion": 1, "name": "Pack",
+			"files": [{"projectID": 1, "fileID": 2, "required": true}, {"projectID": 1, "fileID": 3, "required": true}],
+			"overrides": "files"}`,
+		"files/resourcepacks/pack.zip": "pack",
+	})
+	raw, err := ImportCurseForge(file, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Loader != (loader.Raw{Type: "neoforge", Version: "1.20.1-47.1.82"}) {
+		t.Errorf("got loader %+v, want the primary loader", raw.Loader)
+	}
+	if len(raw.Mods.Items) != 2 || raw.Mods.Items[0].Name != "curse-1" || raw.Mods.Items[1].Name != "curse-1-2" {
+		t.Errorf("got mods %+v, want them named uniquely by project ID", raw.Mods.Items)
+	}
+	if data := readFile(filepath.Join(dir, OverridesDir, "resourcepacks", "pack.zip")); data != "pack" {
+		t.Errorf("overrides not extracted from the manifest's directory: %s", data)
+	}
+
+	zipFile(t, file, map[string]string{"manifest.json": `{"manifestType": "minecraftModpack",
+		"minecraft": {"version": "1.20.1", "modLoaders": [{"id": "quilt-0.20.0", "primary": true}]}}`})
+	if _, err := ImportCurseForge(file, dir); err == nil {
+		t.Error("unsupported loader accepted")
+	}
+}
@@ -0,0 +1,335 @@
+package pack
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"crypto/sha512"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/spec"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Modrinth file environments.
+const (
+	envRequired    = "required"
+	envOptional    = "optional"
+	envUnsupported = "unsupported"
+)
+
+// modrinthHosts are the hosts Modrinth modpacks may download files from.
+// Other mods are bundled into the overrides.
+var modrinthHosts = []string{"cdn.modrinth.com", "github.com", "raw.githubusercontent.com", "gitlab.com"}
+
+// modrinthLoaders are the loaders by their Modrinth dependency IDs.
+var modrinthLoaders = map[string]string{"forge": "forge", "neoforge": "neoforge", "fabric-loader": "fabric"}
+
+// modrinthOverrides are the override directories of Modrinth modpacks, by
+// the side they are installed on.
+var modrinthOverrides = map[string]string{
+	mod.SideBoth: "overrides", mod.SideClient: "client-overrides", mod.SideServer: "server-overrides",
+}
+
+// modrinthIndex values act as JSON import and export containers for the
+// modrinth.index.json files of Modrinth modpacks.
+type modrinthIndex struct {
+	FormatVersion int               `json:"formatVersion"`
+	Game          string            `json:"game"`
+	VersionId     string            `json:"versionId"`
+	Name          string            `json:"name"`
+	Summary       string            `json:"summary,omitempty"`
+	Files         []modrinthFile    `json:"files"`
+	Dependencies  map[string]string `json:"dependencies"`
+}
+
+// modrinthFile values are the files listed in Modrinth modpack indexes.
+type modrinthFile struct {
+	Path      string            `json:"path"`
+	Hashes    map[string]string `json:"hashes"`
+	Env       *modrinthEnv      `json:"env,omitempty"`
+	Downloads []string          `json:"downloads"`
+	FileSize  int64             `json:"fileSize"`
+}
+
+// modrinthEnv values declare whether a file is required, optional or
+// unsupported on each side.
+type modrinthEnv struct {
+	Client string `json:"client"`
+	Server string `json:"server"`
+}
+
+// ImportModrinth returns a new Raw spec of the given Modrinth modpack, for
+// a spec in the directory 'dir'. The jars of the index are looked up on
+// Modrinth by hash and become Modrinth mods, or else URL mods. Files only
+// supported on one side are installed on that side, and optional files
+// become optional mods, selected by default. The overrides are extracted
+// next to the spec: the configs of the common overrides become the spec's
+// config overlay, and jars become local mods of the side of their
+// overrides.
+func ImportModrinth(file, dir string) (*spec.Raw, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var index modrinthIndex
+	if err := readJSON(&r.Reader, "modrinth.index.json", &index); err != nil {
+		return nil, err
+	}
+	if index.Game != "minecraft" {
+		return nil, fmt.Errorf("error: unsupported Modrinth modpack game %q", index.Game)
+	} else if index.FormatVersion != 1 {
+		return nil, fmt.Errorf("error: unsupported Modrinth modpack format %d", index.FormatVersion)
+	}
+
+	this := spec.Raw{SpecVersion: spec.SpecVersion, Name: index.Name, Version: index.VersionId,
+		Mods: mod.RawDirectory{Items: []mod.Raw{}}}
+	for id, version := range index.Dependencies {
+		if id == "minecraft" {
+			continue
+		}
+		name, ok := modrinthLoaders[id]
+		if !ok {
+			return nil, fmt.Errorf("error: unsupported mod loader %q", id)
+		}
+		if this.Loader, err = newLoader(name, version, index.Dependencies["minecraft"]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Identify the jars of the index on Modrinth
+	files, hashes, skipped := []modrinthFile{}, []string{}, map[string]*struct{}{}
+	for _, el := range index.Files {
+		if path.Dir(el.Path) != "mods" || !strings.HasSuffix(el.Path, ".jar") {
+			skipped[el.Path] = new(struct{})
+			continue
+		} else if el.Hashes["sha512"] == "" && el.Hashes["sha1"] == "" {
+			return nil, fmt.Errorf("error: %s has no hashes", el.Path)
+		} else if len(el.Downloads) == 0 {
+			return nil, fmt.Errorf("error: %s has no downloads", el.Path)
+		}
+		files = append(files, el)
+		if el.Hashes["sha512"] != "" {
+			hashes = append(hashes, el.Hashes["sha512"])
+		}
+	}
+	found, err := mod.IdentifyModrinth(hashes)
+	if err != nil {
+		return nil, err
+	}
+	names, imported, identified := map[string]*struct{}{}, 0, 0
+	for _, el := range files {
+		side, optional := el.Env.side()
+		if side == "" {
+			skipped[el.Path] = new(struct{})
+			continue
+		}
+		raw := mod.Raw{Url: el.Downloads[0], Checksum: "sha1:" + el.Hashes["sha1"]}
+		if el.Hashes["sha512"] != "" {
+			raw.Checksum = "sha512:" + el.Hashes["sha512"]
+		}
+		if id := found[el.Hashes["sha512"]]; id != nil {
+			raw, identified = *id, identified+1
+		}
+		if raw.Name == "" {
+			raw.Name = fileName(el.Path)
+		}
+		raw.Name = uniqueName(raw.Name, names)
+		raw.Side, raw.Optional, raw.Default = side, optional, optional
+		if raw.Side == mod.SideBoth {
+			raw.Side = ""
+		}
+		this.Mods.Items, imported = append(this.Mods.Items, raw), imported+1
+	}
+
+	// Extract the overrides of each side next to the spec
+	bundled := 0
+	for _, side := range []string{mod.SideBoth, mod.SideClient, mod.SideServer} {
+		name := modrinthOverrides[side]
+		extracted, err := extract(&r.Reader, name, filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, el := range extracted {
+			top := strings.SplitN(el, "/", 2)[0]
+			if top == spec.ConfigDir && side == mod.SideBoth {
+				this.Config.Overlay = name + "/" + spec.ConfigDir
+			} else if top != "mods" {
+				skipped[name+"/"+top] = new(struct{})
+			}
+		}
+		if side == mod.SideBoth {
+			side = ""
+		}
+		local, err := localMods(filepath.Join(dir, name, "mods"), dir, side, names)
+		if err != nil {
+			return nil, err
+		}
+		this.Mods.Items, bundled = append(this.Mods.Items, local...), bundled+len(local)
+	}
+	printSkipped(skipped, dir)
+	fmt.Fprintf(Log, "Imported %d mods (%d identified on Modrinth) and %d bundled mods.\n",
+		imported, identified, bundled)
+	return &this, nil
+}
+
+// side returns the side a file with the environment is installed on, and
+// whether it is optional. Files without an environment are required on
+// both sides, and the side is empty if the file is unsupported on both.
+func (this *modrinthEnv) side() (string, bool) {
+	if this == nil {
+		return mod.SideBoth, false
+	}
+	client, server := this.Client != envUnsupported, this.Server != envUnsupported
+	optional := this.Client != envRequired && this.Server != envRequired
+	switch {
+	case client && server:
+		return mod.SideBoth, optional
+	case client:
+		return mod.SideClient, optional
+	case server:
+		return mod.SideServer, optional
+	}
+	return "", false
+}
+
+// newModrinthEnv returns the environment of a mod installed on the given
+// side.
+func newModrinthEnv(side string, optional bool) *modrinthEnv {
+	value := envRequired
+	if optional {
+		value = envOptional
+	}
+	this := modrinthEnv{value, value}
+	if side == mod.SideClient {
+		this.Server = envUnsupported
+	} else if side == mod.SideServer {
+		this.Client = envUnsupported
+	}
+	return &this
+}
+
+// ExportModrinth writes the Spec as a Modrinth modpack (.mrpack) to the
+// given file. Mods downloaded from hosts allowed by Modrinth are listed in
+// the index with the hashes of their local copies, as optional if they
+// belong to a feature. Other mods, and the configs, are bundled into the
+// overrides of their side; inactive mods that would be bundled are left
+// out. The version of the pack defaults to "1.0.0".
+func ExportModrinth(s *spec.Spec, file string, src *Sources) error {
+	if s.Loader == nil {
+		return fmt.Errorf("error: exporting a Modrinth modpack requires a loader")
+	}
+	version, err := loaderVersion(s.Loader)
+	if err != nil {
+		return err
+	}
+	index := modrinthIndex{FormatVersion: 1, Game: "minecraft", VersionId: s.Version,
+		Name: s.Name, Files: []modrinthFile{},
+		Dependencies: map[string]string{"minecraft": s.Loader.Minecraft()}}
+	for id, name := range modrinthLoaders {
+		if name == s.Loader.Name() {
+			index.Dependencies[id] = version
+		}
+	}
+	if index.Name == "" {
+		index.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	if index.VersionId == "" {
+		index.VersionId = "1.0.0"
+	}
+	optional := map[net.Downloadable]bool{}
+	for _, feature := range s.Mods.Features {
+		for _, el := range feature.Items {
+			optional[el] = true
+		}
+	}
+
+	// Locate the bundled files before writing anything
+	bundled, skipped := map[string]string{}, 0
+	mods := append(append(net.Downloadables{}, s.Mods.Items...), s.Mods.Inactive...)
+	for i, el := range mods {
+		side := mod.SideBoth
+		if sided, ok := el.(mod.Sided); ok && sided.Side() != "" {
+			side = sided.Side()
+		}
+		if !ModrinthHosted(el) {
+			if i >= len(s.Mods.Items) {
+				skipped++
+				continue
+			}
+			local, err := find(src.Mods, el)
+			if err != nil {
+				return err
+			}
+			bundled[modrinthOverrides[side]+"/mods/"+el.Filename()] = local
+			continue
+		}
+		local, err := find(src.Mods, el)
+		if err != nil {
+			return err
+		}
+		sums, err := net.FileChecksums(local, sha1.New(), sha512.New())
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(local)
+		if err != nil {
+			return err
+		}
+		index.Files = append(index.Files, modrinthFile{Path: "mods/" + el.Filename(),
+			Hashes: map[string]string{"sha1": sums[0], "sha512": sums[1]},
+			Env:    newModrinthEnv(side, optional[el]), Downloads: []string{el.Url()},
+			FileSize: info.Size()})
+	}
+	sort.Slice(index.Files, func(i, j int) bool { return index.Files[i].Path < index.Files[j].Path })
+	for _, el := range s.Config.Items {
+		local, err := find(src.Configs, el)
+		if err != nil {
+			return err
+		}
+		overrides := modrinthOverrides[s.Config.FileSide(el.Filename())]
+		bundled[overrides+"/config/"+el.Filename()] = local
+	}
+
+	w, err := create(file)
+	if err != nil {
+		return err
+	}
+	if err := w.addJSON("modrinth.index.json", &index); err != nil {
+		w.abort()
+		return err
+	}
+	if err := addBundled(w, bundled); err != nil {
+		w.abort()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if skipped > 0 {
+		fmt.Fprintf(Log, "Left out %d inactive mods that are not downloaded from Modrinth.\n", skipped)
+	}
+	fmt.Fprintf(Log, "Exported %d downloaded mods and %d bundled files.\n", len(index.Files), len(bundled))
+	return nil
+}
+
+// ModrinthHosted returns true iff the file is downloaded from a host that
+// Modrinth modpacks may download from.
+func ModrinthHosted(dl net.Downloadable) bool {
+	u, err := url.Parse(dl.Url())
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	for _, el := range modrinthHosts {
+		if u.Host == el {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package pack
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"github.com/faceless-saint/m3/lib/loader"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/spec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModrinthRoundTrip(t *testing.T) {
+	quiet(t)
+	sum := sha512.Sum512([]byte("sodium"))
+	hash := hex.EncodeToString(sum[:])
+	version := `{"id": "VERSION1", "project_id": "AABBCCDD", "version_number": "0.5.3",
+		"files": [{"hashes": {"sha512": "` + hash + `"}, "url": "https://cdn.modrinth.com/sodium.jar", "primary": true}]}`
+	serve(t, map[string]string{
+		"GET /version/VERSION1": version,
+		"POST /version_files":   `{"` + hash + `": ` + version + `}`,
+		"GET /projects":         `[{"id": "AABBCCDD", "slug": "sodium"}]`,
+	})
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"modpack.json": `{"name": "Pack", "version": "2.0",
+			"loader": {"type": "forge", "version": "1.20.1-47.2.0"},
+			"config": {"overlay": "cfg", "sides": {"options.txt": "client"}},
+			"mods": {"items": [
+				{"name": "sodium", "modrinth": "AABBCCDD:VERSION1", "side": "client"},
+				{"name": "library", "url": "https://github.com/o/r/releases/download/v1/library.jar", "optional": true, "default": true},
+				{"name": "extra", "url": "https://example.com/extra.jar", "side": "server"},
+				{"name": "old", "url": "https://example.com/old.jar", "optional": true}
+			]}}`,
+		"cfg/jei.toml":    "jei",
+		"cfg/options.txt": "options",
+	})
+	s, err := spec.FromFile(filepath.Join(dir, "modpack.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Config.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	s.Mods.Select(mod.Selection{"old": false})
+	mods := filepath.Join(dir, "mods")
+	for i, data := range []string{"sodium", "library", "extra"} {
+		writeFiles(t, mods, map[string]string{s.Mods.Items[i].Filename(): data})
+	}
+	file := filepath.Join(dir, "pack.mrpack")
+	if err := ExportModrinth(s, file, &Sources{Mods: []string{mods}}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	raw, err := ImportModrinth(file, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Name != "Pack" || raw.Version != "2.0" {
+		t.Errorf("got pack %s %s, want Pack 2.0", raw.Name, raw.Version)
+	}
+	if raw.Loader != (loader.Raw{Type: "forge", Version: "1.20.1-47.2.0"}) {
+		t.Errorf("got loader %+v", raw.Loader)
+	}
+	got := []string{}
+	for _, el := range raw.Mods.Items {
+		ref := el.Modrinth + el.Url + el.Path
+		if el.Optional {
+			ref += " optional"
+		}
+		got = append(got, el.Name+" "+ref+" "+el.Side)
+	}
+	// Unidentified mods are named after their files, and inactive mods that
+	// would be bundled are left out
+	library, extra := s.Mods.Items[1].Filename(), s.Mods.Items[2].Filename()
+	want := []string{
+		fileName(library) + " https://github.com/o/r/releases/download/v1/library.jar optional ",
+		"sodium AABBCCDD:VERSION1 client",
+		fileName(extra) + " server-overrides/mods/" + extra + " server",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got mods %q, want %q", got, want)
+	}
+	if raw.Config.Overlay != "overrides/config" {
+		t.Errorf("got config overlay %q", raw.Config.Overlay)
+	}
+	for _, el := range []struct{ Path, Data string }{
+		{"overrides/config/jei.toml", "jei"},
+		{"client-overrides/config/options.txt", "options"},
+		{"server-overrides/mods/" + extra, "extra"},
+	} {
+		if data := readFile(filepath.Join(out, filepath.FromSlash(el.Path))); data != el.Data {
+			t.Errorf("got %s %q, want %q", el.Path, data, el.Data)
+		}
+	}
+}
+
+func TestImportModrinthSkipsUnsupported(t *testing.T) {
+	log := quiet(t)
+	serve(t, map[string]string{"POST /version_files": `{}`})
+	dir := t.TempDir()
+	file := filepath.Join(dir, "pack.mrpack")
+	zipFile(t, file, map[string]string{"modrinth.index.json": `{"formatVersion": 1, "game": "minecraft",
+		"versionId": "1.0", "name": "Pack", "dependencies": {"minecraft": "1.20.1", "neoforge": "20.4.80"},
+		"files": [
+			{"path": "mods/a.jar", "hashes": {"sha512": "aa"}, "downloads": ["https://cdn.modrinth.com/a.jar"]},
+			{"path": "mods/b.jar", "hashes": {"sha512": "bb"}, "downloads": ["https://cdn.modrinth.com/b.jar"],
+			 "env": {"client": "unsupported", "server": "unsupported"}},
+			{"path": "mods/c.jar", "hashes": {"sha1": "cc"}, "downloads": ["https://cdn.modrinth.com/c.jar"],
+			 "env": {"client": "optional", "server": "unsupported"}},
+			{"path": "resourcepacks/d.zip", "hashes": {"sha512": "dd"}, "downloads": ["https://cdn.modrinth.com/d.zip"]}
+		]}`})
+	raw, err := ImportModrinth(file, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Loader != (loader.Raw{Type: "neoforge", Version: "1.20.1-20.4.80"}) {
+		t.Errorf("got loader %+v", raw.Loader)
+	}
+	if len(raw.Mods.Items) != 2 || raw.Mods.Items[0].Name != "a" || raw.Mods.Items[1].Name != "c" {
+		t.Fatalf("got mods %+v, want a and c", raw.Mods.Items)
+	}
+	if c := raw.Mods.Items[1]; c.Side != mod.SideClient || !c.Optional || !c.Default || c.Checksum != "sha1:cc" {
+		t.Errorf("got mod %+v, want an optional client mod selected by default", c)
+	}
+	if !strings.Contains(log.String(), "Imported 2 mods (0 identified on Modrinth)") {
+		t.Errorf("got log %q, want 2 mods imported", log.String())
+	}
+	if !strings.Contains(log.String(), "mods/b.jar") || !strings.Contains(log.String(), "resourcepacks/d.zip") {
+		t.Errorf("got log %q, want the skipped files", log.String())
+	}
+}
@@ -0,0 +1,274 @@
+/* Pack is a library for converting between modpack specifications and the
+ * modpack formats of other launchers, such as CurseForge modpack zips.
+ * Packs are imported into Raw specs, with their bundled files extracted
+ * next to the spec, and exported from fully resolved Specs.
+ */
+package pack
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/loader"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/net"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Log is the destination for informational messages about imported and
+// exported packs.
+var Log io.Writer = os.Stdout
+
+// OverridesDir is the directory, next to the spec, that the bundled files
+// of imported packs are extracted into.
+const OverridesDir = "overrides"
+
+// invalidName matches the characters replaced in mod names derived from
+// file names.
+var invalidName = regexp.MustCompile("[^a-z0-9_.-]+")
+
+// Sources values locate the local copies of the mods and configs bundled
+// into exported packs. Each file is read from the first directory it is
+// found in, or else from the spec's directory for local mods.
+type Sources struct {
+	Mods    []string
+	Configs []string
+}
+
+// find returns the local copy of the file in the given directories.
+func find(dirs []string, dl net.Downloadable) (string, error) {
+	for _, dir := range dirs {
+		file := filepath.Join(dir, filepath.FromSlash(dl.Filename()))
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	if local, ok := dl.(net.LocalDownloadable); ok {
+		return local.Source(), nil
+	}
+	return "", fmt.Errorf("error: no local copy of %s found", dl.Filename())
+}
+
+// loaderVersion returns the version of the loader as named by modpack
+// formats: Forge versions are not prefixed with the Minecraft version, and
+// Fabric versions are resolved.
+func loaderVersion(l loader.Loader) (string, error) {
+	p, ok := l.(loader.Provider)
+	if !ok {
+		return "", fmt.Errorf("error: unsupported mod loader %s", l.Name())
+	}
+	id := l.Name()
+	if id == "fabric" {
+		id = "fabricloader"
+	}
+	if version := p.Provides()[id]; version != "" {
+		return version, nil
+	}
+	return "", fmt.Errorf("error: unknown %s version", l.Name())
+}
+
+// newLoader returns the Raw loader of the given type and version, as named
+// by modpack formats, for the given Minecraft version.
+func newLoader(name, version, minecraft string) (loader.Raw, error) {
+	switch name {
+	case "forge":
+		return loader.Raw{Type: name, Version: minecraft + "-" + version}, nil
+	case "neoforge":
+		if minecraft == "1.20.1" && !strings.HasPrefix(version, "1.") {
+			// NeoForge for 1.20.1 is versioned like Forge
+			version = minecraft + "-" + version
+		}
+		return loader.Raw{Type: name, Version: version}, nil
+	case "fabric":
+		return loader.Raw{Type: name, Minecraft: minecraft, Version: version}, nil
+	}
+	return loader.Raw{}, fmt.Errorf("error: unsupported mod loader %q", name)
+}
+
+// localMods returns the local mods of the jars in the given directory,
+// relative to the spec's directory 'base'. Mods are named by the mod ID in
+// their metadata, or else by their file name, avoiding the names in use.
+// Mods are installed on the given side, or else on the side their metadata
+// declares.
+func localMods(dir, base, side string, names map[string]*struct{}) ([]mod.Raw, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jar"))
+	if err != nil {
+		return nil, err
+	}
+	mods := []mod.Raw{}
+	for _, file := range files {
+		rel, err := filepath.Rel(base, file)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := net.FileChecksum(file, net.DefaultHash())
+		if err != nil {
+			return nil, err
+		}
+		raw := mod.Raw{Path: filepath.ToSlash(rel), Checksum: "sha256:" + sum}
+		raw.Name = fileName(file)
+		if meta, err := mod.ReadMetadata(file); err == nil && len(meta) > 0 {
+			raw.Name = meta[0].Id
+			if meta[0].Side == mod.SideClient || meta[0].Side == mod.SideServer {
+				raw.Side = meta[0].Side
+			}
+		}
+		if side != "" {
+			raw.Side = side
+		}
+		raw.Name = uniqueName(raw.Name, names)
+		mods = append(mods, raw)
+	}
+	return mods, nil
+}
+
+// fileName returns the mod name derived from the name of the jar.
+func fileName(file string) string {
+	name := strings.TrimSuffix(strings.ToLower(path.Base(filepath.ToSlash(file))), ".jar")
+	return strings.Trim(invalidName.ReplaceAllString(name, "-"), "-")
+}
+
+// uniqueName returns the name, suffixed with a number if it is already in
+// use, and marks it as used.
+func uniqueName(name string, names map[string]*struct{}) string {
+	unique := name
+	for n := 2; ; n++ {
+		if _, ok := names[unique]; !ok {
+			break
+		}
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+	names[unique] = new(struct{})
+	return unique
+}
+
+// readJSON decodes the JSON file of the zip archive with the given name.
+func readJSON(r *zip.Reader, name string, v interface{}) error {
+	for _, el := range r.File {
+		if el.Name != name {
+			continue
+		}
+		f, err := el.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := json.NewDecoder(f).Decode(v); err != nil {
+			return fmt.Errorf("error: invalid %s: %v", name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("error: %s not found in pack", name)
+}
+
+// extract writes the files of the zip archive below the directory 'prefix'
+// into the directory 'dir', returning the extracted paths relative to
+// prefix. Paths leaving the target directory are rejected.
+func extract(r *zip.Reader, prefix, dir string) ([]string, error) {
+	extracted := []string{}
+	for _, el := range r.File {
+		if !strings.HasPrefix(el.Name, prefix+"/") || strings.HasSuffix(el.Name, "/") {
+			continue
+		}
+		rel := path.Clean(strings.TrimPrefix(el.Name, prefix+"/"))
+		if rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+			return nil, fmt.Errorf("error: invalid file path in pack: %s", el.Name)
+		}
+		file := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := extractFile(el, file); err != nil {
+			return nil, err
+		}
+		extracted = append(extracted, rel)
+	}
+	return extracted, nil
+}
+
+// extractFile writes a single file of a zip archive to the given path.
+func extractFile(el *zip.File, file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	src, err := el.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// writer values write zip archives, such as exported packs.
+type writer struct {
+	*zip.Writer
+	file *os.File
+}
+
+// create returns a writer for a new zip archive at the given path. The
+// archive is written to a temporary file, and only moved into place by
+// Close.
+func create(file string) (*writer, error) {
+	f, err := os.Create(file + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &writer{zip.NewWriter(f), f}, nil
+}
+
+// addJSON writes the value into the archive as an indented JSON file.
+func (this *writer) addJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	w, err := this.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// addFile copies the local file into the archive.
+func (this *writer) addFile(name, file string) error {
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	w, err := this.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, src)
+	return err
+}
+
+// Close finishes the archive and moves it into place.
+func (this *writer) Close() error {
+	if err := this.Writer.Close(); err != nil {
+		this.file.Close()
+		return err
+	}
+	if err := this.file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(this.file.Name(), strings.TrimSuffix(this.file.Name(), ".tmp"))
+}
+
+// abort discards the archive.
+func (this *writer) abort() {
+	this.file.Close()
+	os.Remove(this.file.Name())
+}
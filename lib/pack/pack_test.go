@@ -0,0 +1,160 @@
+package pack
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/spec"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// serve starts a stand-in Modrinth and CurseForge API server answering
+// each "METHOD /path" route with its JSON body, and 404 otherwise.
+func serve(t *testing.T, routes map[string]string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	api, curse, key := mod.ModrinthApi, mod.CurseApi, mod.CurseApiKey
+	mod.ModrinthApi, mod.CurseApi, mod.CurseApiKey = srv.URL, srv.URL, "key"
+	t.Cleanup(func() { mod.ModrinthApi, mod.CurseApi, mod.CurseApiKey = api, curse, key })
+}
+
+// quiet discards the informational messages of the test, returning them.
+func quiet(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	log, specLog := Log, spec.Log
+	Log, spec.Log = &buf, ioutil.Discard
+	t.Cleanup(func() { Log, spec.Log = log, specLog })
+	return &buf
+}
+
+// zipFile writes a zip archive with the given entries, by name, to the file.
+func zipFile(t *testing.T, file string, entries map[string]string) {
+	names := []string{}
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(entries[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeFiles writes the files with the given contents, by path relative to
+// the directory.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readFile returns the contents of the file, or the error message.
+func readFile(file string) string {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "pack.zip")
+	zipFile(t, archive, map[string]string{
+		"manifest.json":              "{}",
+		"overrides/config/jei.toml":  "jei",
+		"overrides/mods/extra.jar":   "extra",
+		"overrides/scripts/":         "",
+		"overrides-extra/config.txt": "other",
+	})
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	target := filepath.Join(dir, "out")
+	extracted, err := extract(&r.Reader, "overrides", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"config/jei.toml", "mods/extra.jar"}
+	if fmt.Sprint(extracted) != fmt.Sprint(want) {
+		t.Errorf("extracted %v, want %v", extracted, want)
+	}
+	if data := readFile(filepath.Join(target, "config", "jei.toml")); data != "jei" {
+		t.Errorf("got extracted config %q", data)
+	}
+}
+
+func TestExtractZipSlip(t *testing.T) {
+	for _, name := range []string{"overrides/../evil.txt", "overrides/config/../../../evil.txt",
+		"overrides/..", "overrides//etc/evil.txt"} {
+		dir := t.TempDir()
+		archive := filepath.Join(dir, "pack.zip")
+		zipFile(t, archive, map[string]string{name: "evil"})
+		r, err := zip.OpenReader(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := filepath.Join(dir, "out")
+		_, err = extract(&r.Reader, "overrides", target)
+		r.Close()
+		if err == nil {
+			t.Errorf("%s: path leaving the target directory accepted", name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "evil.txt")); err == nil {
+			t.Errorf("%s: file written outside the target directory", name)
+		}
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	names := map[string]*struct{}{}
+	got := []string{}
+	for _, el := range []string{"jei", "jei", "sodium", "jei"} {
+		got = append(got, uniqueName(el, names))
+	}
+	if fmt.Sprint(got) != "[jei jei-2 sodium jei-3]" {
+		t.Errorf("got names %v", got)
+	}
+	if name := fileName("mods/Just Enough Items (1.20.1).jar"); name != "just-enough-items-1.20.1" {
+		t.Errorf("got file name %q", name)
+	}
+}
@@ -0,0 +1,98 @@
+/* Plan is a library for describing filesystem changes before they are
+ * made. Decision logic computes a Plan without side-effects, which can
+ * then be reviewed, serialized, and applied.
+ */
+package plan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Op values identify the kind of change an Action makes.
+type Op string
+
+const (
+	// Download fetches a new file from a remote source.
+	Download Op = "download"
+	// Overwrite replaces an outdated file with a fresh download.
+	Overwrite Op = "overwrite"
+	// Enable restores a previously disabled file.
+	Enable Op = "enable"
+	// Disable renames a file with a ".disabled" extension.
+	Disable Op = "disable"
+	// Delete removes a file or directory tree.
+	Delete Op = "delete"
+)
+
+// DisabledExt is the extension given to disabled files.
+const DisabledExt = ".disabled"
+
+// Action values describe a single filesystem change.
+type Action struct {
+	Op Op
+	// Path is the file or directory affected by the Action.
+	Path string
+	// Target is the destination path of an Enable action.
+	Target string `json:",omitempty"`
+	// Url is the remote source of a Download or Overwrite action.
+	Url string `json:",omitempty"`
+}
+
+func (this Action) String() string {
+	switch this.Op {
+	case Enable:
+		return fmt.Sprintf("%-9s %s -> %s", this.Op, this.Path, this.Target)
+	default:
+		return fmt.Sprintf("%-9s %s", this.Op, this.Path)
+	}
+}
+
+// Apply performs the filesystem side-effects of the Action. Download
+// actions have no local side-effect, and Overwrite actions only remove
+// the outdated file; the new files are fetched by the caller afterwards.
+func (this Action) Apply() error {
+	switch this.Op {
+	case Disable:
+		return os.Rename(this.Path, this.Path+DisabledExt)
+	case Enable:
+		return os.Rename(this.Path, this.Target)
+	case Delete, Overwrite:
+		return os.RemoveAll(this.Path)
+	case Download:
+		return os.MkdirAll(filepath.Dir(this.Path), 0755)
+	default:
+		return fmt.Errorf("error: unknown plan operation %q", this.Op)
+	}
+}
+
+// Plan values are ordered lists of Actions.
+type Plan []Action
+
+// Add appends a new Action to the Plan.
+func (this *Plan) Add(op Op, path string) {
+	*this = append(*this, Action{Op: op, Path: path})
+}
+
+// Apply performs every Action in the Plan in order, stopping at the
+// first failure.
+func (this Plan) Apply() error {
+	for _, el := range this {
+		if err := el.Apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count returns the number of Actions in the Plan with the given Op.
+func (this Plan) Count(op Op) int {
+	n := 0
+	for _, el := range this {
+		if el.Op == op {
+			n++
+		}
+	}
+	return n
+}
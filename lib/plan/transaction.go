@@ -0,0 +1,264 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// StateDir is the directory, relative to the installation directory,
+// holding the staging area, backups, copies and journal of a Transaction.
+const StateDir = ".m3"
+
+const (
+	journalFile = "journal.json"
+	stagingDir  = "staging"
+	backupDir   = "backup"
+	copiesDir   = "copies"
+)
+
+// Step values record a single file move made by a Transaction. Copy Steps
+// instead record a file preserved before it was changed outside of a Plan:
+// To holds a copy of the original file, or is empty if there was none.
+type Step struct {
+	From string
+	To   string
+	Copy bool
+}
+
+// Transaction values apply Plans reversibly. Downloads are staged in a
+// private directory and moved into place only once complete, and files
+// are moved into a backup directory rather than deleted. Every move is
+// recorded in an on-disk journal before it is made, so an interrupted
+// Transaction can be rolled back to the exact previous state. The journal
+// is only written once the first file is changed.
+type Transaction struct {
+	dir   string
+	Steps []Step
+}
+
+// Begin starts a new Transaction using the state directory 'dir'. An
+// error is returned if an earlier Transaction was never completed.
+func Begin(dir string) (*Transaction, error) {
+	if Pending(dir) {
+		return nil, fmt.Errorf("error: a previous installation was interrupted - run 'rollback' first")
+	}
+	// Discard leftovers from completed or never started Transactions
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	this := Transaction{dir: dir}
+	if err := os.MkdirAll(this.Staging(), 0755); err != nil {
+		return nil, err
+	}
+	return &this, nil
+}
+
+// Pending returns true iff the state directory holds the journal of an
+// incomplete Transaction.
+func Pending(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, journalFile))
+	return err == nil
+}
+
+// Rollback reverts the incomplete Transaction recorded in the state
+// directory 'dir'. Journaled paths are relative to the parent of 'dir'.
+func Rollback(dir string) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, journalFile))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("error: no interrupted installation to roll back")
+	} else if err != nil {
+		return err
+	}
+	this := Transaction{dir: dir}
+	if err := json.Unmarshal(data, &this); err != nil {
+		return fmt.Errorf("error: invalid journal: %v", err)
+	}
+	return this.Rollback()
+}
+
+// Staging returns the root of the Transaction's staging directory.
+func (this *Transaction) Staging() string {
+	return filepath.Join(this.dir, stagingDir)
+}
+
+// StagingPath returns the staging location for the given file path.
+func (this *Transaction) StagingPath(path string) string {
+	return filepath.Join(this.Staging(), path)
+}
+
+// Apply performs every Action in the Plan. Downloaded files are taken
+// from their staging location, which must already be populated.
+func (this *Transaction) Apply(p Plan) error {
+	for _, el := range p {
+		var err error
+		switch el.Op {
+		case Disable:
+			err = this.move(el.Path, el.Path+DisabledExt)
+		case Enable:
+			err = this.move(el.Path, el.Target)
+		case Delete:
+			err = this.backup(el.Path)
+		case Overwrite:
+			if err = this.backup(el.Path); err == nil {
+				err = this.move(this.StagingPath(el.Path), el.Path)
+			}
+		case Download:
+			err = this.move(this.StagingPath(el.Path), el.Path)
+		default:
+			err = fmt.Errorf("error: unknown plan operation %q", el.Op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Preserve journals the file before it is changed outside of a Plan, such
+// as by a loader installation, so that a rollback restores it. Existing
+// files are copied into the state directory, and files that did not
+// exist are deleted by a rollback. Each file is only preserved once, and
+// paths that are not regular files, such as directories, are rejected.
+func (this *Transaction) Preserve(file string) error {
+	for _, el := range this.Steps {
+		if el.Copy && el.From == file {
+			return nil
+		}
+	}
+	step := Step{From: file, Copy: true}
+	if info, err := os.Lstat(file); err == nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("error: cannot preserve %s - not a regular file", file)
+	} else if err == nil {
+		step.To = filepath.Join(this.dir, copiesDir, strconv.Itoa(len(this.Steps)))
+		if err := copyFile(file, step.To); err != nil {
+			return err
+		}
+	}
+	this.Steps = append(this.Steps, step)
+	return this.save()
+}
+
+// Commit completes the Transaction, discarding the journal, backups and
+// staging area.
+func (this *Transaction) Commit() error {
+	err := os.Remove(filepath.Join(this.dir, journalFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(this.dir)
+}
+
+// Rollback reverts every recorded Step in reverse order, then discards
+// the Transaction. Steps that were journaled but never performed are
+// skipped.
+func (this *Transaction) Rollback() error {
+	for i := len(this.Steps) - 1; i >= 0; i-- {
+		if this.Steps[i].Copy {
+			if err := this.restore(this.Steps[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		from, to := this.path(this.Steps[i].From), this.path(this.Steps[i].To)
+		if _, err := os.Lstat(to); err != nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(from), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(to, from); err != nil {
+			return err
+		}
+	}
+	return this.Commit()
+}
+
+// move journals and performs a move of 'from' to 'to'. Any existing file
+// at the destination is backed up first.
+func (this *Transaction) move(from, to string) error {
+	if _, err := os.Lstat(to); err == nil {
+		if err := this.backup(to); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	this.Steps = append(this.Steps, Step{From: from, To: to})
+	if err := this.save(); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+// restore reverts a Copy Step, replacing the changed file with the copy
+// of the original, or deleting it if there was none. Copies are restored
+// by copying, as the file may be on another device.
+func (this *Transaction) restore(step Step) error {
+	if err := os.RemoveAll(this.path(step.From)); err != nil {
+		return err
+	} else if step.To == "" {
+		return nil
+	}
+	return copyFile(this.path(step.To), this.path(step.From))
+}
+
+// backup moves the given path into the backup directory, if it exists.
+func (this *Transaction) backup(path string) error {
+	if _, err := os.Lstat(path); err != nil {
+		return nil
+	}
+	return this.move(path, filepath.Join(this.dir, backupDir, path))
+}
+
+// path resolves a journaled path relative to the parent of the state
+// directory.
+func (this *Transaction) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(filepath.Dir(this.dir), p)
+}
+
+// copyFile copies the file 'from' to 'to', creating its directory.
+func copyFile(from, to string) error {
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// save atomically writes the journal to disk.
+func (this *Transaction) save() error {
+	data, err := json.Marshal(this)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(this.dir, journalFile)
+	if err := ioutil.WriteFile(file+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
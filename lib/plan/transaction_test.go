@@ -0,0 +1,97 @@
+package plan
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// chdirTemp changes into a new temporary directory for the test.
+func chdirTemp(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestBeginWithoutJournal(t *testing.T) {
+	chdirTemp(t)
+	txn, err := Begin(StateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Pending(StateDir) {
+		t.Error("journal written before any file was changed")
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPreserve(t *testing.T) {
+	chdirTemp(t)
+	if err := ioutil.WriteFile("run.sh", []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	txn, err := Begin(StateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := filepath.Join("libraries", "lib.jar")
+	for _, el := range []string{"run.sh", created, "run.sh"} {
+		if err := txn.Preserve(el); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !Pending(StateDir) {
+		t.Fatal("preserved files were not journaled")
+	}
+	if len(txn.Steps) != 2 {
+		t.Errorf("got %d steps, want each file preserved once", len(txn.Steps))
+	}
+	ioutil.WriteFile("run.sh", []byte("new"), 0755)
+	os.MkdirAll("libraries", 0755)
+	ioutil.WriteFile(created, []byte("new"), 0644)
+
+	// Roll back from the journal, as after an interruption
+	if err := Rollback(StateDir); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile("run.sh"); err != nil || string(data) != "old" {
+		t.Errorf("changed file was not restored: %q, %v", data, err)
+	}
+	if info, err := os.Stat("run.sh"); err == nil && info.Mode().Perm() != 0755 {
+		t.Errorf("restored file has mode %v, want 0755", info.Mode().Perm())
+	}
+	if _, err := os.Stat(created); err == nil {
+		t.Error("created file was not deleted")
+	}
+	if _, err := os.Stat(StateDir); err == nil {
+		t.Error("state directory was not discarded")
+	}
+}
+
+func TestPreserveDirectory(t *testing.T) {
+	chdirTemp(t)
+	if err := os.MkdirAll(filepath.Join("config", "mod"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	txn, err := Begin(StateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Preserve("config"); err == nil {
+		t.Error("directory was preserved")
+	}
+	if err := txn.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join("config", "mod")); err != nil {
+		t.Error("rollback deleted the directory")
+	}
+}
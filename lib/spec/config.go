@@ -1,33 +1,207 @@
 package spec
 
 import (
+	"fmt"
 	"github.com/cavaliercoder/grab"
 	"github.com/faceless-saint/m3/lib/git"
+	"github.com/faceless-saint/m3/lib/mod"
 	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/plan"
+	"hash"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
-// Config values represent Forge mod configurations hosted on GitHub.
+// ConfigDir is the local directory config files are saved to.
+const ConfigDir = "config"
+
+// Config values represent Forge mod configurations hosted on GitHub, and
+// local overlays of config files shipped alongside the spec.
 type Config struct {
 	Repository string
 	Path       string
-	Items      net.Downloadables
+	// Ref is the branch, tag or commit to read configs from. Defaults to
+	// the default branch of the repository.
+	Ref string
+	// Side is the side the configs are installed on: "client", "server"
+	// or "both" (default).
+	Side string
+	// Sides sets the side of individual config files or directories, by
+	// path relative to the config directory, in place of Side.
+	Sides map[string]string
+	// Overlay is a directory of config files relative to the spec's
+	// directory, installed over the configs of the repository.
+	Overlay string
+	Items   net.Downloadables
+	// filter is the side the configs are filtered for, if any.
+	filter string
+}
+
+// FileSide returns the side the given config file is installed on: the
+// side of the longest path in Sides that is the file or one of its
+// directories, or else Side.
+func (this *Config) FileSide(file string) string {
+	side, length := this.Side, -1
+	for key, value := range this.Sides {
+		key = strings.Trim(path.Clean("/"+key), "/")
+		if (file == key || strings.HasPrefix(file, key+"/") || key == "") && len(key) > length {
+			side, length = value, len(key)
+		}
+	}
+	if side == "" {
+		return mod.SideBoth
+	}
+	return side
+}
+
+// Filter drops the configs that are not installed on the given side.
+// Configs that are not resolved yet are filtered once they are.
+func (this *Config) Filter(side string) {
+	if side == mod.SideBoth {
+		side = ""
+	}
+	this.filter = side
+	this.Items = this.filtered(this.Items)
+}
+
+// filtered returns the configs of the list that are installed on the side
+// the Config is filtered for.
+func (this *Config) filtered(items net.Downloadables) net.Downloadables {
+	if this.filter == "" || items == nil {
+		return items
+	}
+	kept := net.Downloadables{}
+	for _, el := range items {
+		if side := this.FileSide(el.Filename()); side == mod.SideBoth || side == this.filter {
+			kept = append(kept, el)
+		}
+	}
+	return kept
+}
+
+// Resolve populates the Items list with every config file found in the
+// repository and the overlay. The repository is only queried once.
+func (this *Config) Resolve() error {
+	if this.Items != nil || (this.Repository == "" && this.Overlay == "") {
+		return nil
+	}
+	this.Items = net.Downloadables{}
+	if this.Repository != "" {
+		repo, err := git.NewRepository(this.Repository)
+		if err != nil {
+			return err
+		}
+		repo.Ref = this.Ref
+		configs, err := repo.Aggregate(this.Path)
+		if err != nil {
+			return err
+		}
+		for _, el := range configs.JustFiles() {
+			conf := el
+			conf.Path = strings.Replace(conf.Path, this.Path+"/", "", 1)
+			this.Items = append(this.Items, &conf)
+		}
+	}
+	if err := this.applyOverlay(); err != nil {
+		return err
+	}
+	this.Items = this.filtered(this.Items)
+	return nil
+}
+
+// applyOverlay adds the files of the overlay to the Items, replacing the
+// items with the same file names.
+func (this *Config) applyOverlay() error {
+	if this.Overlay == "" {
+		return nil
+	}
+	overlay := map[string]net.Downloadable{}
+	err := filepath.Walk(this.Overlay, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(this.Overlay, file)
+		if err != nil {
+			return err
+		}
+		sum, err := net.FileChecksum(file, net.DefaultHash())
+		if err != nil {
+			return err
+		}
+		overlay[filepath.ToSlash(rel)] = &overlayFile{filepath.ToSlash(rel), file, sum}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error: failed to read config overlay: %v", err)
+	}
+	items := net.Downloadables{}
+	for _, el := range this.Items {
+		if _, ok := overlay[el.Filename()]; !ok {
+			items = append(items, el)
+		}
+	}
+	names := []string{}
+	for name := range overlay {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		items = append(items, overlay[name])
+	}
+	this.Items = items
+	return nil
+}
+
+// overlayFile values represent the config files of an overlay. They
+// implement net.LocalDownloadable.
+type overlayFile struct {
+	path     string
+	source   string
+	checksum string
+}
+
+func (this *overlayFile) Url() string      { return "file:" + filepath.ToSlash(this.source) }
+func (this *overlayFile) Filename() string { return this.path }
+func (this *overlayFile) Checksum() string { return this.checksum }
+func (this *overlayFile) Hash() hash.Hash  { return net.DefaultHash() }
+func (this *overlayFile) Source() string   { return this.source }
+
+// Plan computes the changes that Fetch would make to the local config
+// directory, without modifying it. Local config files that differ from
+// the repository are overwritten.
+func (this *Config) Plan() (plan.Plan, error) {
+	if err := this.Resolve(); err != nil {
+		return nil, err
+	}
+	p := plan.Plan{}
+	for _, el := range this.Items {
+		file := filepath.Join(ConfigDir, el.Filename())
+		if _, err := os.Stat(file); err != nil {
+			p = append(p, plan.Action{Op: plan.Download, Path: file, Url: el.Url()})
+			continue
+		}
+		match, err := net.CheckFile(file, el.Checksum(), el.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			p = append(p, plan.Action{Op: plan.Overwrite, Path: file, Url: el.Url()})
+		}
+	}
+	return p, nil
 }
 
 // Fetch downloads all config files from the repository.
 func (this *Config) Fetch(num int, verbose bool) (<-chan *grab.Response, int, error) {
-	repo, err := git.NewRepository(this.Repository)
+	p, err := this.Plan()
 	if err != nil {
 		return nil, 0, err
 	}
-	configs, err := repo.Aggregate(this.Path)
-	if err != nil {
+	if err := p.Apply(); err != nil {
 		return nil, 0, err
 	}
-	for _, el := range configs.JustFiles() {
-		conf := el
-		conf.Path = strings.Replace(conf.Path, this.Path+"/", "", 1)
-		this.Items = append(this.Items, &conf)
-	}
-	return this.Items.GetFiles("config", num)
+	return this.Items.GetFiles(ConfigDir, num)
 }
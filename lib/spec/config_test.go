@@ -0,0 +1,63 @@
+package spec
+
+import (
+	"github.com/faceless-saint/m3/lib/mod"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigSides(t *testing.T) {
+	dir := t.TempDir()
+	for _, el := range []string{"common.toml", "journeymap/client.cfg", "journeymap/server.cfg"} {
+		file := filepath.Join(dir, filepath.FromSlash(el))
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err := ioutil.WriteFile(file, []byte(el), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sides := map[string]string{"journeymap/": "client", "journeymap/server.cfg": "server"}
+	for side, want := range map[string][]string{
+		mod.SideServer: {"common.toml", "journeymap/server.cfg"},
+		mod.SideClient: {"common.toml", "journeymap/client.cfg"},
+		mod.SideBoth:   {"common.toml", "journeymap/client.cfg", "journeymap/server.cfg"},
+	} {
+		// Configs are filtered before they are resolved
+		c := Config{Overlay: dir, Sides: sides}
+		c.Filter(side)
+		if err := c.Resolve(); err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, el := range c.Items {
+			got = append(got, el.Filename())
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: got configs %v, want %v", side, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: got configs %v, want %v", side, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestConfigFileSide(t *testing.T) {
+	c := Config{Side: mod.SideServer, Sides: map[string]string{"journeymap": mod.SideClient}}
+	for file, want := range map[string]string{
+		"common.toml":           mod.SideServer,
+		"journeymap/client.cfg": mod.SideClient,
+		"journeymap.toml":       mod.SideServer,
+	} {
+		if got := c.FileSide(file); got != want {
+			t.Errorf("%s: got side %s, want %s", file, got, want)
+		}
+	}
+	if got := (&Config{}).FileSide("common.toml"); got != mod.SideBoth {
+		t.Errorf("got default side %s, want both", got)
+	}
+}
@@ -0,0 +1,351 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/loader"
+	"github.com/faceless-saint/m3/lib/mod"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// resolve merges the specs extended by the Raw spec at 'base' into it,
+// returning the result. 'seen' holds the locations of the specs already
+// visited, to detect cycles.
+func resolve(raw *Raw, base string, seen map[string]*struct{}) (*Raw, error) {
+	if err := raw.normalize(); err != nil {
+		return nil, err
+	}
+	if raw.Extends == "" {
+		if len(raw.Mods.Remove) > 0 || len(raw.Mods.Unignore) > 0 {
+			return nil, fmt.Errorf("error: 'remove' and 'unignore' require 'extends'")
+		}
+		return raw, nil
+	}
+	location, err := locate(raw.Extends, base)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := seen[location]; ok {
+		return nil, fmt.Errorf("error: spec %s extends itself", location)
+	}
+	seen[location] = new(struct{})
+
+	parentBase := location
+	if !isRemote(location) {
+		parentBase = filepath.Dir(location)
+	}
+	data, err := readJSON(location)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(Log, "Extends: %s\n", location)
+	if problems := Validate(data); len(problems) > 0 {
+		return nil, &ValidationError{location, problems}
+	}
+	var parent Raw
+	if err := json.Unmarshal(data, &parent); err != nil {
+		return nil, err
+	}
+	resolved, err := resolve(&parent, parentBase, seen)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolved.rebase(parentBase, base); err != nil {
+		return nil, err
+	}
+	return Merge(resolved, raw)
+}
+
+// Merge returns the spec resulting from applying the Raw spec 'child' on
+// top of 'parent'. Mods are matched by name: mods of the child replace
+// the parent's mods in place, new mods are appended in order, and the
+// mods named by the child's Mods.Remove are dropped. Ignore entries are
+// combined, except those named by Mods.Unignore. The loader, Java version
+// and config source are overridden by the fields the child sets, and the
+// spec version is the newer of the two.
+func Merge(parent, child *Raw) (*Raw, error) {
+	this := Raw{SpecVersion: parent.SpecVersion, Name: parent.Name, Version: parent.Version,
+		Loader: mergeLoader(parent.Loader, child.Loader), Java: parent.Java, Config: parent.Config}
+	if child.SpecVersion > this.SpecVersion {
+		this.SpecVersion = child.SpecVersion
+	}
+	if child.Java != 0 {
+		this.Java = child.Java
+	}
+	for _, el := range []struct{ To, From *string }{
+		{&this.Name, &child.Name}, {&this.Version, &child.Version},
+	} {
+		if *el.From != "" {
+			*el.To = *el.From
+		}
+	}
+	if child.Config.Repository != "" {
+		this.Config = child.Config
+	} else {
+		for _, el := range []struct{ To, From *string }{
+			{&this.Config.Path, &child.Config.Path},
+			{&this.Config.Ref, &child.Config.Ref},
+			{&this.Config.Side, &child.Config.Side},
+			{&this.Config.Overlay, &child.Config.Overlay},
+		} {
+			if *el.From != "" {
+				*el.To = *el.From
+			}
+		}
+		if len(child.Config.Sides) > 0 {
+			sides := map[string]string{}
+			for _, el := range []map[string]string{this.Config.Sides, child.Config.Sides} {
+				for key, side := range el {
+					sides[key] = side
+				}
+			}
+			this.Config.Sides = sides
+		}
+	}
+
+	// Merge the mods by name
+	removed := map[string]*struct{}{}
+	for _, el := range child.Mods.Remove {
+		removed[el] = new(struct{})
+	}
+	index := map[string]int{}
+	for _, el := range parent.Mods.Items {
+		if _, ok := removed[el.Name]; ok {
+			delete(removed, el.Name)
+			continue
+		}
+		index[el.Name] = len(this.Mods.Items)
+		this.Mods.Items = append(this.Mods.Items, el)
+	}
+	for _, el := range child.Mods.Remove {
+		if _, ok := removed[el]; ok {
+			return nil, fmt.Errorf("error: spec removes unknown mod %q", el)
+		}
+	}
+	for _, el := range child.Mods.Items {
+		if i, ok := index[el.Name]; ok {
+			this.Mods.Items[i] = el
+		} else {
+			index[el.Name] = len(this.Mods.Items)
+			this.Mods.Items = append(this.Mods.Items, el)
+		}
+	}
+
+	// Combine the ignore entries
+	unignored := map[string]*struct{}{}
+	for _, el := range child.Mods.Unignore {
+		unignored[el] = new(struct{})
+	}
+	for _, el := range append(append([]string{}, parent.Mods.Ignore...), child.Mods.Ignore...) {
+		if _, ok := unignored[el]; !ok {
+			unignored[el] = new(struct{})
+			this.Mods.Ignore = append(this.Mods.Ignore, el)
+		}
+	}
+
+	// Merge the groups by name, dropping removed mods
+	for _, groups := range []map[string]mod.RawGroup{parent.Mods.Groups, child.Mods.Groups} {
+		for name, group := range groups {
+			mods := []string{}
+			for _, el := range group.Mods {
+				if _, ok := index[el]; ok {
+					mods = append(mods, el)
+				}
+			}
+			if this.Mods.Groups == nil {
+				this.Mods.Groups = map[string]mod.RawGroup{}
+			}
+			this.Mods.Groups[name] = mod.RawGroup{Default: group.Default, Mods: mods}
+		}
+	}
+	return &this, nil
+}
+
+// mergeLoader returns the loader resulting from applying 'child' on top
+// of 'parent'. Changing the loader type replaces the parent's loader, and
+// changing the version drops its checksums.
+func mergeLoader(parent, child loader.Raw) loader.Raw {
+	if child.Type != "" && loaderType(child) != loaderType(parent) {
+		return child
+	}
+	this := parent
+	if child.Minecraft != "" {
+		this.Minecraft = child.Minecraft
+	}
+	if child.Version != "" && child.Version != parent.Version {
+		this.Version, this.Checksum, this.ServerChecksum = child.Version, "", ""
+	}
+	if child.Checksum != "" {
+		this.Checksum = child.Checksum
+	}
+	if child.ServerChecksum != "" {
+		this.ServerChecksum = child.ServerChecksum
+	}
+	if child.Type != "" {
+		this.Type = child.Type
+	}
+	return this
+}
+
+// loaderType returns the type of the loader, which defaults to "forge".
+func loaderType(raw loader.Raw) string {
+	if raw.Type == "" {
+		return "forge"
+	}
+	return raw.Type
+}
+
+// rebase rewrites the local mod paths of the Raw spec, given relative to
+// 'from', to be relative to 'to'. Paths relative to a remote spec become
+// URLs. Config overlays of remote specs are dropped.
+func (this *Raw) rebase(from, to string) error {
+	if this.Config.Overlay != "" {
+		if isRemote(from) {
+			this.Config.Overlay = ""
+		} else if path, err := rebasePath(this.Config.Overlay, from, to); err != nil {
+			return err
+		} else {
+			this.Config.Overlay = path
+		}
+	}
+	for i, el := range this.Mods.Items {
+		if el.Path == "" {
+			continue
+		}
+		if isRemote(from) {
+			base, err := url.Parse(from)
+			if err != nil {
+				return err
+			}
+			ref, err := url.Parse(filepath.ToSlash(el.Path))
+			if err != nil {
+				return err
+			}
+			this.Mods.Items[i].Url, this.Mods.Items[i].Path = base.ResolveReference(ref).String(), ""
+			continue
+		}
+		path, err := rebasePath(el.Path, from, to)
+		if err != nil {
+			return err
+		}
+		this.Mods.Items[i].Path = path
+	}
+	return nil
+}
+
+// rebasePath returns the local path, given relative to the directory
+// 'from', relative to 'to' instead.
+func rebasePath(path, from, to string) (string, error) {
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(from, path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if !isRemote(to) {
+		dir, err := filepath.Abs(to)
+		if err != nil {
+			return "", err
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path), nil
+}
+
+// Render returns the Raw spec as indented JSON, leaving out empty values.
+func (this *Raw) Render() ([]byte, error) {
+	data, err := json.Marshal(this)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	data, err = json.MarshalIndent(compact(value), "", "    ")
+	return append(data, '\n'), err
+}
+
+// compact returns the JSON value without its empty members.
+func compact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, el := range v {
+			if el = compact(el); el == nil {
+				delete(v, key)
+			} else {
+				v[key] = el
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+	case []interface{}:
+		list := []interface{}{}
+		for _, el := range v {
+			if el = compact(el); el != nil {
+				list = append(list, el)
+			}
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		return list
+	case string:
+		if v == "" {
+			return nil
+		}
+	case bool:
+		if !v {
+			return nil
+		}
+	case float64:
+		if v == 0 {
+			return nil
+		}
+	}
+	return value
+}
+
+// locate returns the location of the spec referenced by 'ref' from a spec
+// at 'base'.
+func locate(ref, base string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "github:"):
+		split := strings.SplitN(strings.TrimPrefix(ref, "github:"), "/", 3)
+		if len(split) < 3 {
+			return "", fmt.Errorf("error: invalid github spec reference %q", ref)
+		}
+		return gitHubUrl(split[0]+"/"+split[1], split[2]), nil
+	case strings.HasPrefix(ref, "//"):
+		return "https:" + ref, nil
+	case isRemote(ref):
+		return ref, nil
+	case isRemote(base):
+		u, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+		r, err := url.Parse(filepath.ToSlash(ref))
+		if err != nil {
+			return "", err
+		}
+		return u.ResolveReference(r).String(), nil
+	}
+	path := filepath.FromSlash(ref)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return filepath.Abs(path)
+}
+
+// isRemote returns true iff the location is an HTTP(S) URL.
+func isRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
@@ -0,0 +1,36 @@
+package spec
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtendsCycle(t *testing.T) {
+	Log = ioutil.Discard
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	ioutil.WriteFile(a, []byte(`{"extends": "b.json"}`), 0644)
+	ioutil.WriteFile(b, []byte(`{"extends": "a.json"}`), 0644)
+	_, err := ReadFile(a)
+	if err == nil {
+		t.Fatal("cycle accepted")
+	}
+	if !strings.Contains(err.Error(), a) {
+		t.Errorf("got error %q, want the cycle reported at %s", err, a)
+	}
+}
+
+func TestMergeSpecVersion(t *testing.T) {
+	for _, el := range []struct{ Parent, Child, Want int }{{1, 2, 2}, {2, 1, 2}, {2, 0, 2}} {
+		merged, err := Merge(&Raw{SpecVersion: el.Parent}, &Raw{SpecVersion: el.Child})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if merged.SpecVersion != el.Want {
+			t.Errorf("merged spec versions %d and %d into %d, want %d",
+				el.Parent, el.Child, merged.SpecVersion, el.Want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec file formats.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// Formats lists the supported spec file formats.
+var Formats = []string{FormatJSON, FormatYAML, FormatTOML}
+
+// tomlTable matches a TOML table header line.
+var tomlTable = regexp.MustCompile(`(?m)^\s*\[\[?[A-Za-z0-9_."' -]+\]\]?\s*(#.*)?$`)
+
+// FormatOf returns the format of the spec file with the given name or URL,
+// by its extension, or an empty string if it is unknown.
+func FormatOf(name string) string {
+	if i := strings.IndexAny(name, "?#"); i >= 0 && isRemote(name) {
+		name = name[:i]
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return ""
+}
+
+// formatOfType returns the spec format of the given HTTP content type, or
+// an empty string if it is unknown.
+func formatOfType(contentType string) string {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch media {
+	case "application/json":
+		return FormatJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML
+	case "application/toml", "text/toml", "text/x-toml":
+		return FormatTOML
+	}
+	return ""
+}
+
+// detect returns the format of the spec data read from 'name', determined
+// by its extension, then by its content type, then by its contents.
+func detect(name, contentType string, data []byte) string {
+	if format := FormatOf(name); format != "" {
+		return format
+	}
+	if format := formatOfType(contentType); format != "" {
+		return format
+	}
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(trimmed) == 0 || trimmed[0] == '{':
+		return FormatJSON
+	case tomlTable.Match(trimmed):
+		return FormatTOML
+	}
+	return FormatYAML
+}
+
+// Convert translates spec data from one format to another. Only the data
+// is kept: comments and formatting are lost, and object keys are sorted.
+func Convert(data []byte, from, to string) ([]byte, error) {
+	value, err := decode(data, from)
+	if err != nil {
+		return nil, err
+	}
+	return encode(value, to)
+}
+
+// toJSON returns the spec data in the given format as JSON.
+func toJSON(data []byte, format string) ([]byte, error) {
+	if format == FormatJSON {
+		return data, nil
+	}
+	value, err := decode(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// decode returns the generic value of the data in the given format. Objects
+// are decoded as map[string]interface{}, arrays as []interface{}, and
+// numbers as int64 or float64.
+func decode(data []byte, format string) (interface{}, error) {
+	var value interface{}
+	var err error
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&value)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &value)
+	case FormatTOML:
+		var table map[string]interface{}
+		_, err = toml.Decode(string(data), &table)
+		value = table
+	default:
+		return nil, fmt.Errorf("error: unknown spec format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error: invalid %s spec: %v", strings.ToUpper(format), err)
+	}
+	return generic(value), nil
+}
+
+// encode returns the generic value in the given format.
+func encode(value interface{}, format string) ([]byte, error) {
+	buf := bytes.Buffer{}
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(value); err != nil {
+			return nil, err
+		}
+	case FormatYAML:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(value); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		// TOML has no null value
+		table, ok := dropNulls(value).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("error: TOML specs must be tables")
+		}
+		if err := toml.NewEncoder(&buf).Encode(table); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("error: unknown spec format %q", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// generic converts the decoded value into its format-independent form.
+func generic(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, el := range v {
+			v[key] = generic(el)
+		}
+	case map[interface{}]interface{}:
+		object := map[string]interface{}{}
+		for key, el := range v {
+			object[fmt.Sprint(key)] = generic(el)
+		}
+		return object
+	case []interface{}:
+		for i, el := range v {
+			v[i] = generic(el)
+		}
+	case []map[string]interface{}:
+		list := []interface{}{}
+		for _, el := range v {
+			list = append(list, generic(el))
+		}
+		return list
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return n
+		}
+		n, _ := v.Float64()
+		return n
+	case int:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return value
+}
+
+// dropNulls returns the generic value without its null members.
+func dropNulls(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, el := range v {
+			if el == nil {
+				delete(v, key)
+			} else {
+				v[key] = dropNulls(el)
+			}
+		}
+	case []interface{}:
+		list := []interface{}{}
+		for _, el := range v {
+			if el != nil {
+				list = append(list, dropNulls(el))
+			}
+		}
+		return list
+	}
+	return value
+}
@@ -0,0 +1,123 @@
+package spec
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// formatSpec is a spec using nested objects, arrays, integers and booleans.
+const formatSpec = `{
+    "specVersion": 1,
+    "name": "Pack",
+    "loader": {"type": "forge", "version": "1.20.1-47.2.0"},
+    "java": 17,
+    "config": {"overlay": "config", "sides": {"journeymap/": "client"}},
+    "mods": {
+        "items": [
+            {"name": "jei", "curse": "238222:4567890"},
+            {"name": "sodium", "modrinth": "AANobbMI:4GyXKCLd", "side": "client", "optional": true, "default": true}
+        ],
+        "groups": {"performance": {"default": false, "mods": ["sodium"]}}
+    }
+}`
+
+func TestConvertRoundTrip(t *testing.T) {
+	want, err := decode([]byte(formatSpec), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Floats and nulls are kept as well
+	want.(map[string]interface{})["ratio"] = 0.5
+	want.(map[string]interface{})["extra"] = nil
+	spec, err := encode(want, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{FormatYAML, FormatTOML} {
+		data, err := Convert(spec, FormatJSON, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if got := detect("", "", data); got != format {
+			t.Errorf("%s: detected converted spec as %s", format, got)
+		}
+		if data, err = Convert(data, format, FormatJSON); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		got, err := decode(data, FormatJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format == FormatTOML {
+			// TOML has no null value
+			got.(map[string]interface{})["extra"] = nil
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", format, got, want)
+		}
+	}
+}
+
+func TestReadFileFormats(t *testing.T) {
+	dir := t.TempDir()
+	var want *Raw
+	for _, format := range Formats {
+		data, err := Convert([]byte(formatSpec), FormatJSON, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(dir, "modpack."+format)
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		raw, err := ReadFile(file)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if want == nil {
+			want = raw
+		} else if !reflect.DeepEqual(raw, want) {
+			got, _ := json.Marshal(raw)
+			expected, _ := json.Marshal(want)
+			t.Errorf("%s: got spec %s, want %s", format, got, expected)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, el := range []struct {
+		Name, ContentType, Data, Want string
+	}{
+		{"modpack.yml", "application/json", "{}", FormatYAML},
+		{"https://example.com/modpack.toml?ref=main", "", "{}", FormatTOML},
+		{"modpack", "text/yaml; charset=utf-8", "{}", FormatYAML},
+		{"modpack", "text/plain", " {\"name\": \"Pack\"}", FormatJSON},
+		{"modpack", "", "", FormatJSON},
+		{"modpack", "", "name = \"Pack\"\n\n[loader]\ntype = \"forge\"\n", FormatTOML},
+		{"modpack", "", "name = \"Pack\"\n[[mods.items]] # first mod\nname = \"jei\"\n", FormatTOML},
+		{"modpack", "", "name: Pack\nloader:\n  type: forge\n", FormatYAML},
+	} {
+		if got := detect(el.Name, el.ContentType, []byte(el.Data)); got != el.Want {
+			t.Errorf("%s %q: got format %s, want %s", el.Name, el.Data, got, el.Want)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for format, data := range map[string]string{
+		FormatJSON: `{"name": `,
+		FormatYAML: "name: [Pack",
+		FormatTOML: "name = ",
+		"xml":      "<spec/>",
+	} {
+		if _, err := decode([]byte(data), format); err == nil {
+			t.Errorf("%s: invalid spec decoded", format)
+		}
+	}
+	if _, err := Convert([]byte(`["jei"]`), FormatJSON, FormatTOML); err == nil {
+		t.Error("TOML spec without a table encoded")
+	}
+}
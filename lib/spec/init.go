@@ -0,0 +1,84 @@
+package spec
+
+import (
+	"fmt"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultLocalDir is the directory, next to the spec, that Init copies
+// unidentified jars into.
+const DefaultLocalDir = "local"
+
+// invalidName matches the characters replaced in mod names derived from
+// file names.
+var invalidName = regexp.MustCompile("[^a-z0-9_.-]+")
+
+// Init returns a new Raw spec listing every jar in the mod directory 'dir'.
+// Jars are identified by mod.Identify, and listed as Modrinth, CurseForge
+// or URL mods with the checksums of the local jars. Unidentified jars are
+// copied into the DefaultLocalDir of the spec's directory 'base' and
+// listed as local mods. Mods are named by the mod ID in their metadata, or
+// else by their file name, and keep the side their metadata declares.
+func Init(dir, base string) (*Raw, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jar"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	raws, err := mod.Identify(files)
+	if err != nil {
+		return nil, err
+	}
+	this := Raw{SpecVersion: SpecVersion, Mods: mod.RawDirectory{Items: []mod.Raw{}}}
+	names, identified := map[string]*struct{}{}, 0
+	for i, file := range files {
+		raw := mod.Raw{}
+		if raws[i] != nil {
+			raw, identified = *raws[i], identified+1
+		}
+		raw.Name = strings.TrimSuffix(strings.ToLower(filepath.Base(file)), ".jar")
+		raw.Name = strings.Trim(invalidName.ReplaceAllString(raw.Name, "-"), "-")
+		if meta, err := mod.ReadMetadata(file); err == nil && len(meta) > 0 {
+			raw.Name = meta[0].Id
+			if meta[0].Side == mod.SideClient || meta[0].Side == mod.SideServer {
+				raw.Side = meta[0].Side
+			}
+		}
+		for n, name := 2, raw.Name; ; n++ {
+			if _, ok := names[raw.Name]; !ok {
+				break
+			}
+			raw.Name = fmt.Sprintf("%s-%d", name, n)
+		}
+		names[raw.Name] = new(struct{})
+
+		if raws[i] == nil {
+			// Keep a copy of the jar next to the spec
+			sum, err := net.FileChecksum(file, net.DefaultHash())
+			if err != nil {
+				return nil, err
+			}
+			raw.Checksum = "sha256:" + sum
+			raw.Path = filepath.ToSlash(filepath.Join(DefaultLocalDir, filepath.Base(file)))
+			source := raw
+			source.Path = file
+			dl, err := mod.New(&source)
+			if err != nil {
+				return nil, err
+			}
+			target := filepath.Join(base, filepath.FromSlash(raw.Path))
+			if err := net.CopyFile(dl.(net.LocalDownloadable), target); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(Log, "Unidentified: %s (copied to %s)\n", filepath.Base(file), target)
+		}
+		this.Mods.Items = append(this.Mods.Items, raw)
+	}
+	fmt.Fprintf(Log, "Identified %d of %d jars.\n", identified, len(files))
+	return &this, nil
+}
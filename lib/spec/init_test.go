@@ -0,0 +1,75 @@
+package spec
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/net"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	Log = ioutil.Discard
+	dir, base := t.TempDir(), t.TempDir()
+	for name, data := range map[string]string{"Sodium.jar": "identified", "My Mod.jar": "unidentified"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sum, err := net.FileChecksum(filepath.Join(dir, "Sodium.jar"), sha512.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Identify only the first jar on Modrinth
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/version_files" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"%[1]s": {"id": "VERSION1", "project_id": "AABBCCDD", "files": [
+			{"hashes": {"sha512": "%[1]s"}, "url": "https://cdn.modrinth.com/sodium.jar", "primary": true}
+		]}}`, sum)
+	}))
+	defer srv.Close()
+	api, key := mod.ModrinthApi, mod.CurseApiKey
+	mod.ModrinthApi, mod.CurseApiKey = srv.URL, ""
+	defer func() { mod.ModrinthApi, mod.CurseApiKey = api, key }()
+
+	raw, err := Init(dir, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw.Mods.Items) != 2 {
+		t.Fatalf("got %d mods, want 2", len(raw.Mods.Items))
+	}
+	local, identified := raw.Mods.Items[0], raw.Mods.Items[1]
+	if identified.Name != "sodium" || identified.Modrinth != "AABBCCDD:VERSION1" || identified.Path != "" {
+		t.Errorf("got identified mod %+v, want the Modrinth mod sodium", identified)
+	}
+	if local.Name != "my-mod" || local.Path != "local/My Mod.jar" {
+		t.Errorf("got unidentified mod %+v, want the local mod my-mod", local)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(base, DefaultLocalDir, "My Mod.jar"))
+	if err != nil || string(data) != "unidentified" {
+		t.Errorf("unidentified jar was not copied into %s: %v", DefaultLocalDir, err)
+	}
+	if _, err := os.Stat(filepath.Join(base, DefaultLocalDir, "Sodium.jar")); err == nil {
+		t.Error("identified jar was copied")
+	}
+
+	// The local mod is valid relative to the spec's directory
+	local.Path = filepath.Join(base, filepath.FromSlash(local.Path))
+	dl, err := mod.New(&local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := net.CheckFile(local.Path, dl.Checksum(), dl.Hash()); err != nil || !ok {
+		t.Errorf("local copy does not match the checksum %s", local.Checksum)
+	}
+}
@@ -0,0 +1,327 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/git"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/net"
+	"github.com/faceless-saint/m3/lib/plan"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultLockFile is the default file name for Spec lockfiles.
+const DefaultLockFile = "modpack.lock.json"
+
+// Lock values record the fully resolved contents of a Spec, allowing
+// byte-identical installations.
+type Lock struct {
+	Loader *LockedFile `json:",omitempty"`
+	Mods   []LockedFile
+	Config *LockedConfig `json:",omitempty"`
+}
+
+// LockedFile values record the resolved download source and content of
+// a file.
+type LockedFile struct {
+	// Name is the name of the spec mod the file is locked for.
+	Name     string `json:",omitempty"`
+	Filename string
+	Url      string
+	Size     int64
+	Sha256   string
+	Sha512   string
+	// Side is the side the file is installed on, if not both.
+	Side string `json:",omitempty"`
+	// ApiUrl is the API download URL of GitHub release assets, used in
+	// place of the public Url if a GitHub token is set.
+	ApiUrl string `json:",omitempty"`
+}
+
+// LockedConfig values record the exact commit and files of a Config.
+type LockedConfig struct {
+	Repository string
+	Path       string
+	Commit     string
+	Files      []LockedConfigFile
+}
+
+// LockedConfigFile values record a config file by its Git blob SHA.
+type LockedConfigFile struct {
+	Path string
+	Url  string
+	Sha  string
+}
+
+// NewLockedFile returns a new LockedFile for the given Downloadable,
+// using the local copy of the file for its size and checksums.
+func NewLockedFile(dl net.Downloadable, file string) (*LockedFile, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := net.FileChecksums(file, sha256.New(), sha512.New())
+	if err != nil {
+		return nil, err
+	}
+	locked := LockedFile{Filename: dl.Filename(), Url: dl.Url(), Size: info.Size(),
+		Sha256: sums[0], Sha512: sums[1]}
+	if named, ok := dl.(mod.Named); ok {
+		locked.Name = named.ModName()
+	}
+	if sided, ok := dl.(mod.Sided); ok && sided.Side() != mod.SideBoth {
+		locked.Side = sided.Side()
+	}
+	if gh, ok := dl.(*mod.GitHubReleaseMod); ok {
+		// Keep the lockfile independent of the GitHub token
+		locked.Url, locked.ApiUrl = gh.PublicUrl(), gh.ApiUrl()
+	}
+	return &locked, nil
+}
+
+// Lock returns a new Lock for the Spec. Local copies of mods are read
+// from 'modDir' if valid, including disabled copies, or from 'cacheDir'
+// otherwise; the loader
+// installer is read from the current directory or 'cacheDir' likewise.
+func (this *Spec) Lock(modDir, cacheDir string) (*Lock, error) {
+	lock := Lock{Mods: []LockedFile{}}
+	for _, el := range this.Mods.Items {
+		file, err := findValid(el, filepath.Join(modDir, el.Filename()),
+			filepath.Join(modDir, el.Filename()+plan.DisabledExt),
+			filepath.Join(cacheDir, el.Filename()))
+		if err != nil {
+			return nil, err
+		}
+		locked, err := NewLockedFile(el, file)
+		if err != nil {
+			return nil, err
+		}
+		lock.Mods = append(lock.Mods, *locked)
+	}
+	if this.Loader != nil {
+		file, err := findValid(this.Loader, this.Loader.Filename(),
+			filepath.Join(cacheDir, this.Loader.Filename()))
+		if err != nil {
+			return nil, err
+		}
+		if lock.Loader, err = NewLockedFile(this.Loader, file); err != nil {
+			return nil, err
+		}
+	}
+	if this.Config.Repository != "" {
+		var err error
+		if lock.Config, err = this.Config.Lock(); err != nil {
+			return nil, err
+		}
+	}
+	return &lock, nil
+}
+
+// Lock resolves the Config's Ref to a commit and returns a LockedConfig
+// recording every config file at that commit.
+func (this *Config) Lock() (*LockedConfig, error) {
+	repo, err := git.NewRepository(this.Repository)
+	if err != nil {
+		return nil, err
+	}
+	repo.Ref = this.Ref
+	commit, err := repo.Commit()
+	if err != nil {
+		return nil, err
+	}
+	pinned := Config{Repository: this.Repository, Path: this.Path, Ref: commit}
+	if err := pinned.Resolve(); err != nil {
+		return nil, err
+	}
+	lock := LockedConfig{this.Repository, this.Path, commit, []LockedConfigFile{}}
+	for _, el := range pinned.Items {
+		lock.Files = append(lock.Files,
+			LockedConfigFile{el.Filename(), el.Url(), el.Checksum()})
+	}
+	return &lock, nil
+}
+
+// ReadLock returns the Lock saved in the given file.
+func ReadLock(file string) (*Lock, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("error: invalid lockfile %s: %v", file, err)
+	}
+	return &lock, nil
+}
+
+// WriteFile saves the Lock to the given file as indented JSON.
+func (this *Lock) WriteFile(file string) error {
+	data, err := json.MarshalIndent(this, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// NewLocked returns a new Spec from the fully resolved Raw spec like New,
+// with the Lock applied by ApplyLock. Remote mods are initialized from
+// their lock entries, matched by name, so that no mod APIs are queried.
+func NewLocked(raw *Raw, base string, lock *Lock) (*Spec, error) {
+	named := map[string]*LockedFile{}
+	for i, el := range lock.Mods {
+		if el.Name != "" {
+			named[el.Name] = &lock.Mods[i]
+		}
+	}
+	s, err := newSpec(raw, base, func(el *mod.Raw, loader, minecraft string) (net.Downloadable, error) {
+		if el.Path != "" {
+			return mod.New(el)
+		}
+		locked, ok := named[el.Name]
+		if !ok {
+			return nil, outdated(el.Name)
+		}
+		return &lockedItem{locked, ""}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, s.ApplyLock(lock)
+}
+
+// outdated returns the error for a spec mod missing from the lockfile.
+func outdated(name string) error {
+	return fmt.Errorf("error: lockfile is out of date - mod %s is not locked, run 'm3 lock' to regenerate it", name)
+}
+
+// ApplyLock replaces the resolved contents of the Spec with those
+// recorded in the Lock, so that installations use exactly the locked
+// files. The Lock must match the Spec's loader version and lock every
+// mod of the Spec.
+func (this *Spec) ApplyLock(lock *Lock) error {
+	if lock.Loader != nil && this.Loader != nil {
+		if lock.Loader.Filename != this.Loader.Filename() {
+			return fmt.Errorf("error: lockfile is out of date - loader %s is locked, but %s is required",
+				lock.Loader.Filename, this.Loader.Filename())
+		}
+		this.Loader.SetChecksum(lock.Loader.Sha512, sha512.New())
+	}
+	files := map[string]*struct{}{}
+	for _, el := range lock.Mods {
+		files[el.Filename] = new(struct{})
+	}
+	// Local mods are still copied from the spec's own files
+	sources, sides := map[string]string{}, map[string]string{}
+	for _, el := range this.Mods.Items {
+		if _, ok := files[el.Filename()]; !ok {
+			name := el.Filename()
+			if named, ok := el.(mod.Named); ok {
+				name = named.ModName()
+			}
+			return outdated(name)
+		}
+		if local, ok := el.(net.LocalDownloadable); ok && local.Source() != "" {
+			sources[el.Filename()] = local.Source()
+		}
+		if sided, ok := el.(mod.Sided); ok {
+			sides[el.Filename()] = sided.Side()
+		}
+	}
+	this.Mods.Items = net.Downloadables{}
+	locked := map[string]net.Downloadable{}
+	for i, el := range lock.Mods {
+		source, ok := sources[el.Filename]
+		if !ok && strings.HasPrefix(el.Url, "file:") {
+			return fmt.Errorf("error: lockfile is out of date - local mod %s is not in the spec",
+				el.Filename)
+		}
+		item := lockedItem{&lock.Mods[i], source}
+		if item.LockedFile.Side == "" {
+			// Lockfiles written before sides were locked
+			item.LockedFile.Side = sides[el.Filename]
+		}
+		this.Mods.Items, locked[el.Filename] = append(this.Mods.Items, &item), &item
+	}
+	// Features select the locked mods in place of the spec's
+	for _, feature := range this.Mods.Features {
+		items := net.Downloadables{}
+		for _, el := range feature.Items {
+			if item, ok := locked[el.Filename()]; ok {
+				items = append(items, item)
+			}
+		}
+		feature.Items = items
+	}
+	this.Config.Items = net.Downloadables{}
+	if lock.Config != nil {
+		this.Config.Ref = lock.Config.Commit
+		for _, el := range lock.Config.Files {
+			this.Config.Items = append(this.Config.Items, &git.Content{
+				Type: "file", Path: el.Path, Download_url: el.Url, Sha: el.Sha})
+		}
+	}
+	// The overlay is shipped with the spec, like local mods
+	if err := this.Config.applyOverlay(); err != nil {
+		return err
+	}
+	this.Config.Items = this.Config.filtered(this.Config.Items)
+	return nil
+}
+
+// lockedItem values implement net.Downloadable, net.HeaderDownloadable,
+// mod.Named and mod.Sided for locked files, and net.LocalDownloadable for
+// locked local mods.
+type lockedItem struct {
+	*LockedFile
+	source string
+}
+
+func (this *lockedItem) Url() string {
+	if git.Token != "" && this.ApiUrl != "" {
+		return this.ApiUrl
+	}
+	return this.LockedFile.Url
+}
+func (this *lockedItem) ModName() string  { return this.Name }
+func (this *lockedItem) Filename() string { return this.LockedFile.Filename }
+func (this *lockedItem) Checksum() string { return this.Sha512 }
+func (this *lockedItem) Hash() hash.Hash  { return sha512.New() }
+func (this *lockedItem) Source() string   { return this.source }
+func (this *lockedItem) Side() string {
+	if this.LockedFile.Side == "" {
+		return mod.SideBoth
+	}
+	return this.LockedFile.Side
+}
+
+// Header returns the extra headers required to download locked GitHub
+// release assets. Other files are downloaded without credentials.
+func (this *lockedItem) Header() http.Header {
+	if this.ApiUrl == "" {
+		return http.Header{}
+	}
+	return mod.GitHubHeader(this.ApiUrl)
+}
+
+// findValid returns the first of the given files that exists and
+// matches the Downloadable's checksum.
+func findValid(dl net.Downloadable, files ...string) (string, error) {
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if ok, err := net.CheckFile(file, dl.Checksum(), dl.Hash()); err != nil {
+			return "", err
+		} else if ok {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("error: no valid copy of %s found", dl.Filename())
+}
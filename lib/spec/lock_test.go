@@ -0,0 +1,175 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/faceless-saint/m3/lib/git"
+	"github.com/faceless-saint/m3/lib/mod"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// lockedSpec returns a new Spec of the given JSON data, locked to files
+// with the filenames of its mods.
+func lockedSpec(t *testing.T, data string) *Spec {
+	Log = ioutil.Discard
+	s, err := FromJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := Lock{Mods: []LockedFile{}}
+	for _, el := range s.Mods.Items {
+		lock.Mods = append(lock.Mods, LockedFile{Filename: el.Filename(), Url: el.Url()})
+	}
+	if err := s.ApplyLock(&lock); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// filenames returns the filenames of the mods.
+func filenames(s *Spec) map[string]bool {
+	files := map[string]bool{}
+	for _, el := range s.Mods.Items {
+		files[el.Filename()] = true
+	}
+	return files
+}
+
+func TestApplyLockKeepsSides(t *testing.T) {
+	s := lockedSpec(t, `{"mods": {"items": [
+		{"name": "common", "url": "https://example.com/common.jar"},
+		{"name": "minimap", "url": "https://example.com/minimap.jar", "side": "client"}
+	]}}`)
+	for _, el := range s.Mods.Items {
+		if _, ok := el.(mod.Sided); !ok {
+			t.Fatalf("locked mod %s has no side", el.Filename())
+		}
+	}
+	s.Filter(mod.SideServer)
+	if len(s.Mods.Items) != 1 || s.Mods.Items[0].Url() != "https://example.com/common.jar" {
+		t.Errorf("server mods are %v, want only common", filenames(s))
+	}
+	if len(s.Mods.Inactive) != 1 {
+		t.Errorf("got %d inactive mods, want the client mod", len(s.Mods.Inactive))
+	}
+}
+
+func TestApplyLockKeepsFeatures(t *testing.T) {
+	s := lockedSpec(t, `{"mods": {"items": [
+		{"name": "common", "url": "https://example.com/common.jar"},
+		{"name": "shaders", "url": "https://example.com/shaders.jar", "optional": true}
+	]}}`)
+	feature := s.Mods.Feature("shaders")
+	if feature == nil || len(feature.Items) != 1 {
+		t.Fatal("feature shaders lost its mod")
+	}
+	s.Mods.Select(mod.Selection{"shaders": false})
+	if len(s.Mods.Items) != 1 || s.Mods.Items[0].Url() != "https://example.com/common.jar" {
+		t.Errorf("selected mods are %v, want only common", filenames(s))
+	}
+}
+
+func TestApplyLockMissingMod(t *testing.T) {
+	Log = ioutil.Discard
+	s, err := FromJSON([]byte(`{"mods": {"items": [
+		{"name": "common", "url": "https://example.com/common.jar"},
+		{"name": "minimap", "url": "https://example.com/minimap.jar"}
+	]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := Lock{Mods: []LockedFile{{Filename: s.Mods.Items[0].Filename()}}}
+	if err := s.ApplyLock(&lock); err == nil {
+		t.Error("mod missing from the lockfile was dropped")
+	}
+}
+
+func TestNewLockedQueriesNoApis(t *testing.T) {
+	Log = ioutil.Discard
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("API queried: %s %s", r.Method, r.URL)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	defer func(api string) { mod.ModrinthApi = api }(mod.ModrinthApi)
+	mod.ModrinthApi = srv.URL
+
+	raw, err := ParseRaw([]byte(`{"mods": {"items": [
+		{"name": "sodium", "modrinth": "AABBCCDD", "side": "client"},
+		{"name": "shaders", "modrinth": "EEFF0011", "optional": true}
+	]}}`), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := Lock{Mods: []LockedFile{
+		{Name: "sodium", Filename: "sodium.jar", Url: "https://cdn.modrinth.com/sodium.jar", Side: mod.SideClient},
+		{Name: "shaders", Filename: "shaders.jar", Url: "https://cdn.modrinth.com/shaders.jar"},
+		{Name: "indium", Filename: "indium.jar", Url: "https://cdn.modrinth.com/indium.jar"},
+	}}
+	s, err := NewLocked(raw, ".", &lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := filenames(s); len(files) != 3 || !files["sodium.jar"] || !files["indium.jar"] {
+		t.Errorf("got mods %v, want all locked mods", files)
+	}
+	if feature := s.Mods.Feature("shaders"); feature == nil || len(feature.Items) != 1 ||
+		feature.Items[0].Filename() != "shaders.jar" {
+		t.Error("feature shaders lost its locked mod")
+	}
+
+	lock.Mods = lock.Mods[1:]
+	if _, err := NewLocked(raw, ".", &lock); err == nil {
+		t.Error("mod missing from the lockfile was dropped")
+	}
+}
+
+func TestLockDisabledMod(t *testing.T) {
+	Log = ioutil.Discard
+	sum := sha256.Sum256([]byte("minimap"))
+	s, err := FromJSON([]byte(`{"mods": {"items": [{"name": "minimap",
+		"url": "https://example.com/minimap.jar", "checksum": "` + hex.EncodeToString(sum[:]) + `"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, name := t.TempDir(), s.Mods.Items[0].Filename()
+	// Mods of the other side are disabled on installation
+	err = ioutil.WriteFile(filepath.Join(dir, name+".disabled"), []byte("minimap"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := s.Lock(dir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Mods) != 1 || lock.Mods[0].Filename != name || lock.Mods[0].Size != 7 {
+		t.Errorf("got locked mods %+v, want %s of 7 bytes", lock.Mods, name)
+	}
+}
+
+func TestLockedGitHubAsset(t *testing.T) {
+	defer func(token string) { git.Token = token }(git.Token)
+	asset := lockedItem{LockedFile: &LockedFile{Url: "https://github.com/o/r/releases/download/v1/m.jar",
+		ApiUrl: "https://api.github.com/repos/o/r/releases/assets/1"}}
+	other := lockedItem{LockedFile: &LockedFile{Url: "https://cdn.modrinth.com/data/p/versions/v/m.jar"}}
+
+	git.Token = ""
+	if asset.Url() != asset.LockedFile.Url || asset.Header().Get("Authorization") != "" {
+		t.Errorf("without a token, got %s with headers %v", asset.Url(), asset.Header())
+	}
+	git.Token = "secret"
+	if asset.Url() != asset.ApiUrl {
+		t.Errorf("with a token, got %s, want the API URL", asset.Url())
+	}
+	header := asset.Header()
+	if header.Get("Authorization") != "Bearer secret" || header.Get("Accept") != "application/octet-stream" {
+		t.Errorf("with a token, got headers %v", header)
+	}
+	if other.Url() != other.LockedFile.Url || other.Header().Get("Authorization") != "" {
+		t.Errorf("credentials sent for %s", other.Url())
+	}
+}
@@ -0,0 +1,83 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/plan"
+	"io"
+)
+
+// Plan values describe every change an installation of a Spec would
+// make to the target directory.
+type Plan struct {
+	Mods   plan.Plan
+	Config plan.Plan
+	Loader plan.Plan
+}
+
+// Plan computes the installation Plan for the Spec in the current
+// directory, using 'modDir' as the mod directory. Loader changes are only
+// included if 'install' is true.
+func (this *Spec) Plan(modDir string, install bool) (*Plan, error) {
+	var err error
+	p := Plan{Loader: plan.Plan{}}
+	if p.Mods, err = this.Mods.Plan(modDir); err != nil {
+		return nil, err
+	}
+	if p.Config, err = this.Config.Plan(); err != nil {
+		return nil, err
+	}
+	if install && this.Loader != nil {
+		if p.Loader, err = this.Loader.Plan(); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+// Empty returns true iff the Plan contains no changes.
+func (this *Plan) Empty() bool {
+	return len(this.Mods) == 0 && len(this.Config) == 0 && len(this.Loader) == 0
+}
+
+// Apply performs the local side-effects of the Plan. Files marked for
+// download must be fetched afterwards.
+func (this *Plan) Apply() error {
+	for _, p := range []plan.Plan{this.Loader, this.Mods, this.Config} {
+		if err := p.Apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Print writes a human readable summary of the Plan.
+func (this *Plan) Print(w io.Writer) {
+	if this.Empty() {
+		fmt.Fprint(w, "No changes.\n")
+		return
+	}
+	sections := []struct {
+		Name string
+		Plan plan.Plan
+	}{{"Loader", this.Loader}, {"Mods", this.Mods}, {"Config", this.Config}}
+	for _, el := range sections {
+		if len(el.Plan) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", el.Name)
+		for _, action := range el.Plan {
+			fmt.Fprintf(w, "\t%v\n", action)
+		}
+	}
+}
+
+// WriteJSON writes the Plan as indented JSON.
+func (this *Plan) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(this, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
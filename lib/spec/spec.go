@@ -1,31 +1,53 @@
 /* Spec is a library containing type definitions and utility functions
  * pertaining to Minecraft modpack specifications. This includes the
- * management of Forge installers and mod configurations. The management
- * of mod files themselves are delegated to the 'm3/lib/mod' package.
+ * management of mod configurations. The management of mod loaders and
+ * mod files themselves are delegated to the 'm3/lib/loader' and
+ * 'm3/lib/mod' packages.
  */
 package spec
 
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/faceless-saint/m3/lib/java"
+	"github.com/faceless-saint/m3/lib/loader"
 	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/net"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 )
 
+// Log is the destination for informational messages about loaded specs.
+var Log io.Writer = os.Stdout
+
 // Spec values represent complete modpack specifications.
 type Spec struct {
-	Forge Installer
-	/* "forge": {
+	// Name and Version identify the modpack when exported.
+	Name    string
+	Version string
+	Loader  loader.Loader
+	/* "loader": {
+	 *      "type": "forge|fabric",
+	 *      "minecraft": "",
 	 *      "version": "",
 	 *      "checksum": "",
 	 *      "serverChecksum": ""
 	 * }
 	 */
+	Java int
+	/* "java": 17
+	 */
 	Config Config
 	/* "config": {
 	 *      "repository": "",
-	 *      "path": ""
+	 *      "path": "",
+	 *      "side": "client|server|both",
+	 *      "sides": {"<path>": "client|server|both"...},
+	 *      "overlay": ""
 	 * }
 	 */
 	Mods mod.Directory
@@ -36,7 +58,8 @@ type Spec struct {
 		 *              "version": "",
 		 *              "checksum":"",
 		 *              "url": "",
-		 *              "curse": ""
+		 *              "curse": "",
+		 *              "side": "client|server|both"
 	     *          }...
 	     *      ],
 	     *      "ignore": [""...]
@@ -46,59 +69,283 @@ type Spec struct {
 
 // Raw values act as JSON import containers for Spec values
 type Raw struct {
-	Forge  RawInstaller
+	// SpecVersion is the version of the spec format. Defaults to 1.
+	SpecVersion int
+	// Extends is the spec this spec is based on: a path relative to this
+	// spec, a URL, or "github:<owner>/<repo>/<path>".
+	Extends string
+	// Name and Version identify the modpack when exported.
+	Name    string
+	Version string
+	// Forge is the legacy form of Loader, implying the "forge" type.
+	Forge  loader.Raw
+	Loader loader.Raw
+	// Java is the required major Java version.
+	Java   int
 	Config Config
 	Mods   mod.RawDirectory
 }
 
-// FromFile returns a new Spec parsed from the given JSON file.
+// FromFile returns a new Spec parsed from the given JSON, YAML or TOML
+// file.
 func FromFile(file string) (*Spec, error) {
-	data, err := ioutil.ReadFile(file)
+	raw, err := ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("Local spec: %s\n", file)
-	return FromJSON(data)
+	return New(raw, filepath.Dir(file))
 }
 
-// FromRemote returns a new Spec parsed from remote JSON data.
+// FromRemote returns a new Spec parsed from remote JSON, YAML or TOML
+// data. The format is detected by extension, content type or contents.
 func FromRemote(url string) (*Spec, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	page, err := ioutil.ReadAll(resp.Body)
+	raw, err := ReadRemote(url)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("Remote spec: %s\n", url)
-	return FromJSON(page)
+	return New(raw, url)
 }
 
 // FromGitHub returns a new Spec parsed from the given GitHub content.
 func FromGitHub(repository, path string) (*Spec, error) {
-	url := fmt.Sprintf("https://raw.githubusercontent.com/%s/master/%s", repository, path)
-	return FromRemote(url)
+	return FromRemote(gitHubUrl(repository, path))
 }
 
 // FromJSON returns a new Spec parsed raw JSON data.
 func FromJSON(data []byte) (*Spec, error) {
+	return FromJSONAt(data, ".")
+}
+
+// FromJSONAt returns a new Spec parsed from raw JSON data, resolving
+// local mod paths and extended specs relative to the given directory or
+// URL.
+func FromJSONAt(data []byte, base string) (*Spec, error) {
+	raw, err := ParseRaw(data, base)
+	if err != nil {
+		return nil, err
+	}
+	return New(raw, base)
+}
+
+// ReadFile returns the fully resolved Raw spec of the given JSON, YAML or
+// TOML file.
+func ReadFile(file string) (*Raw, error) {
+	data, err := readJSON(file)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(Log, "Local spec: %s\n", file)
+	raw, err := parseRaw(data, filepath.Dir(file), file)
+	if verr, ok := err.(*ValidationError); ok && verr.Source == "" {
+		verr.Source = file
+	}
+	return raw, err
+}
+
+// ReadRemote returns the fully resolved Raw spec at the given URL.
+func ReadRemote(url string) (*Raw, error) {
+	page, err := readJSON(url)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(Log, "Remote spec: %s\n", url)
+	raw, err := parseRaw(page, url, url)
+	if verr, ok := err.(*ValidationError); ok && verr.Source == "" {
+		verr.Source = url
+	}
+	return raw, err
+}
+
+// Load returns the contents of the spec at the given path or URL, along
+// with its format.
+func Load(location string) ([]byte, string, error) {
+	var data []byte
+	var contentType string
+	var err error
+	if isRemote(location) {
+		data, contentType, err = get(location)
+	} else {
+		data, err = ioutil.ReadFile(location)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return data, detect(location, contentType, data), nil
+}
+
+// readJSON returns the contents of the spec at the given path or URL as
+// JSON.
+func readJSON(location string) ([]byte, error) {
+	data, format, err := Load(location)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = toJSON(data, format); err != nil {
+		return nil, fmt.Errorf("%v (%s)", err, location)
+	}
+	return data, nil
+}
+
+// ParseRaw returns the fully resolved Raw spec parsed from raw JSON data.
+// The data is validated first, and a ValidationError listing every
+// problem is returned if it is invalid. Extended specs are resolved
+// relative to the given directory or URL and merged into the result.
+func ParseRaw(data []byte, base string) (*Raw, error) {
+	return parseRaw(data, base, "")
+}
+
+// parseRaw is ParseRaw for the spec at the given location, if known, so
+// that specs extending it are reported as cycles.
+func parseRaw(data []byte, base, location string) (*Raw, error) {
+	if problems := Validate(data); len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
 	var raw Raw
-	err := json.Unmarshal(data, &raw)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	seen := map[string]*struct{}{}
+	if location != "" {
+		if location, err := locate(location, "."); err == nil {
+			seen[location] = new(struct{})
+		}
+	}
+	if raw.Extends == "" {
+		return resolve(&raw, base, seen)
+	}
+	merged, err := resolve(&raw, base, seen)
 	if err != nil {
 		return nil, err
 	}
-	mods, err := mod.NewDirectory(&raw.Mods)
+	// Check the merged spec, as shown by 'spec render'
+	if problems := merged.validate(); len(problems) > 0 {
+		return nil, &ValidationError{Source: "(merged)", Problems: problems}
+	}
+	return merged, nil
+}
+
+// New returns a new Spec from the fully resolved Raw spec, resolving local
+// mod paths relative to the given directory or URL.
+func New(raw *Raw, base string) (*Spec, error) {
+	return newSpec(raw, base, mod.NewFor)
+}
+
+// newSpec returns a new Spec like New, initializing each mod with 'newMod'
+// for the loader and Minecraft version of the spec.
+func newSpec(raw *Raw, base string, newMod func(*mod.Raw, string, string) (net.Downloadable, error)) (*Spec, error) {
+	if err := raw.normalize(); err != nil {
+		return nil, err
+	}
+	l, err := loader.New(&raw.Loader)
 	if err != nil {
 		return nil, err
 	}
-	forge, err := NewInstaller(&raw.Forge)
+	name, minecraft := "", ""
+	if l != nil {
+		name, minecraft = l.Name(), l.Minecraft()
+	}
+	mods, err := mod.NewDirectoryWith(&raw.Mods, base, func(el *mod.Raw) (net.Downloadable, error) {
+		return newMod(el, name, minecraft)
+	})
 	if err != nil {
 		return nil, err
 	}
-	spec := Spec{Forge: *forge, Config: raw.Config, Mods: *mods}
-	fmt.Printf("Forge version: %s\nConfig source: %v\n",
-		spec.Forge.Version, spec.Config.Repository)
+	if !mod.ValidSide(raw.Config.Side) {
+		return nil, fmt.Errorf("error: config 'side' must be one of 'client', 'server', 'both'")
+	}
+	for key, side := range raw.Config.Sides {
+		if !mod.ValidSide(side) {
+			return nil, fmt.Errorf("error: config side of %q must be one of 'client', 'server', 'both'", key)
+		}
+	}
+	spec := Spec{Name: raw.Name, Version: raw.Version, Loader: l,
+		Java: raw.Java, Config: raw.Config, Mods: *mods}
+	if spec.Config.Overlay != "" {
+		if isRemote(base) {
+			return nil, fmt.Errorf("error: config overlays require a local spec")
+		}
+		overlay := filepath.FromSlash(spec.Config.Overlay)
+		if !filepath.IsAbs(overlay) {
+			overlay = filepath.Join(base, overlay)
+		}
+		if spec.Config.Overlay, err = filepath.Abs(overlay); err != nil {
+			return nil, err
+		}
+	}
+	if l != nil {
+		fmt.Fprintf(Log, "Loader: %v\n", l)
+	}
+	fmt.Fprintf(Log, "Config source: %v\n", spec.Config.Repository)
 	return &spec, nil
 }
+
+// normalize converts the legacy Forge section into the Loader section.
+func (this *Raw) normalize() error {
+	if this.Forge.Version != "" {
+		if this.Loader.Version != "" || this.Loader.Minecraft != "" {
+			return fmt.Errorf("error: spec defines both 'forge' and 'loader'")
+		}
+		this.Loader, this.Loader.Type = this.Forge, "forge"
+		this.Forge = loader.Raw{}
+	}
+	return nil
+}
+
+// get returns the contents of the given URL and their content type.
+func get(url string) ([]byte, string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("error: failed to get %s: %s", url, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	return data, resp.Header.Get("Content-Type"), err
+}
+
+// gitHubUrl returns the URL of the given file in a GitHub repository.
+func gitHubUrl(repository, path string) string {
+	return fmt.Sprintf("https://raw.githubusercontent.com/%s/master/%s", repository, path)
+}
+
+// JavaVersion returns the major Java version required by the Spec. Unless
+// declared, it is derived from the loader's Minecraft version, or zero if
+// there is no loader.
+func (this *Spec) JavaVersion() int {
+	if this.Java != 0 || this.Loader == nil {
+		return this.Java
+	}
+	return java.Required(this.Loader.Minecraft())
+}
+
+// Provided returns the versions of the mod IDs provided by the loader and
+// the Java runtime, for checking the dependencies of mods.
+func (this *Spec) Provided() map[string]string {
+	mods := map[string]string{}
+	if p, ok := this.Loader.(loader.Provider); ok {
+		mods = p.Provides()
+	}
+	if v := this.JavaVersion(); v != 0 {
+		mods["java"] = strconv.Itoa(v)
+	}
+	return mods
+}
+
+// CheckDependencies returns the issues with the dependencies declared by the
+// given mods, narrowed to the metadata for the Spec's loader, for the given
+// side.
+func (this *Spec) CheckDependencies(mods []mod.Metadata, side string) []mod.Issue {
+	if this.Loader != nil {
+		mods = mod.ForLoader(mods, this.Loader.Name())
+	}
+	return mod.CheckDependencies(mods, this.Provided(), side)
+}
+
+// Filter removes the mods and configs that are not installed on the given
+// side, either "client" or "server". An empty side keeps everything.
+func (this *Spec) Filter(side string) {
+	this.Mods.Filter(side)
+	this.Config.Filter(side)
+}
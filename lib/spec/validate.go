@@ -0,0 +1,319 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faceless-saint/m3/lib/loader"
+	"github.com/faceless-saint/m3/lib/mod"
+	"github.com/faceless-saint/m3/lib/net"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SpecVersion is the newest spec format version supported. Specs without
+// a version use version 1.
+const SpecVersion = 1
+
+// Problem values describe a problem found in a spec, located by its JSON
+// path.
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (this Problem) String() string { return this.Path + ": " + this.Message }
+
+// ValidationError values list every problem found in a spec.
+type ValidationError struct {
+	// Source is the location of the spec, if known.
+	Source   string
+	Problems []Problem
+}
+
+func (this *ValidationError) Error() string {
+	lines := []string{"error: invalid spec"}
+	if this.Source != "" {
+		lines[0] += " " + this.Source
+	}
+	for _, el := range this.Problems {
+		lines = append(lines, "\t"+el.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checksumLengths are the hex-encoded checksum lengths of each supported
+// hash algorithm.
+var checksumLengths = map[string]int{
+	"sha512": 128, "sha256": 64, "sha1": 40, "md5": 32, "git": 40, "murmur2": 8,
+}
+
+// Validate checks the raw JSON data of a spec, returning every problem
+// found: unknown fields, invalid values, missing mod sources, and
+// duplicate mods. Keys are matched case-insensitively, as when parsing.
+func Validate(data []byte) []Problem {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return []Problem{{"$", err.Error()}}
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return []Problem{{"$", "spec must be a JSON object"}}
+	}
+	problems := []Problem{}
+	checkFields(value, reflect.TypeOf(Raw{}), "$", &problems)
+	var raw Raw
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return append(problems, Problem{"$", err.Error()})
+	}
+	return append(problems, raw.validate()...)
+}
+
+// validate checks the values of the Raw spec.
+func (this *Raw) validate() []Problem {
+	problems := []Problem{}
+	add := func(path, format string, a ...interface{}) {
+		problems = append(problems, Problem{path, fmt.Sprintf(format, a...)})
+	}
+	if this.SpecVersion < 0 || this.SpecVersion > SpecVersion {
+		add("$.specVersion", "unsupported spec version %d (supported: 1 to %d)",
+			this.SpecVersion, SpecVersion)
+	}
+
+	// Loader
+	l, path := this.Loader, "$.loader"
+	if this.Forge != (loader.Raw{}) {
+		if this.Loader != (loader.Raw{}) {
+			add("$.forge", "cannot be combined with 'loader'")
+		}
+		if this.Forge.Type != "" {
+			add("$.forge.type", "not supported by the legacy 'forge' block - use 'loader'")
+		}
+		l, path = this.Forge, "$.forge"
+		l.Type = "forge"
+	}
+	if l != (loader.Raw{}) {
+		switch l.Type {
+		case "", "forge", "neoforge":
+			if l.Version == "" {
+				add(path+".version", "missing required %s version", loaderType(l))
+			}
+		case "fabric":
+			if l.Minecraft == "" {
+				add(path+".minecraft", "missing required minecraft version for fabric")
+			}
+		default:
+			add(path+".type", "unknown loader type %q", l.Type)
+		}
+		if msg := checkChecksum(l.Checksum); msg != "" {
+			add(path+".checksum", "%s", msg)
+		}
+		if msg := checkChecksum(l.ServerChecksum); msg != "" {
+			add(path+".serverChecksum", "%s", msg)
+		}
+	}
+	if this.Java != 0 && this.Java < 8 {
+		add("$.java", "invalid java version %d", this.Java)
+	}
+
+	// Config
+	if !mod.ValidSide(this.Config.Side) {
+		add("$.config.side", "must be one of 'client', 'server', 'both'")
+	}
+	for key, side := range this.Config.Sides {
+		if !mod.ValidSide(side) {
+			add(fmt.Sprintf("$.config.sides[%q]", key), "must be one of 'client', 'server', 'both'")
+		}
+	}
+	if this.Config.Repository == "" && this.Extends == "" &&
+		(this.Config.Path != "" || this.Config.Ref != "") {
+		add("$.config.repository", "missing config repository")
+	}
+
+	// Mods
+	names, sources, files := map[string]int{}, map[string]int{}, map[string]int{}
+	for i, el := range this.Mods.Items {
+		path := fmt.Sprintf("$.mods.items[%d]", i)
+		if el.Name == "" {
+			add(path+".name", "missing required mod name")
+		} else if j, ok := names[el.Name]; ok {
+			add(path+".name", "duplicate mod name %q (also $.mods.items[%d])", el.Name, j)
+		} else {
+			names[el.Name] = i
+		}
+		if msg := checkChecksum(el.Checksum); msg != "" {
+			add(path+".checksum", "%s", msg)
+		}
+		if !mod.ValidSide(el.Side) {
+			add(path+".side", "must be one of 'client', 'server', 'both'")
+		}
+		source := modSource(&el)
+		if source == "" {
+			add(path, "missing mod source - need one of 'modrinth', 'curse', 'github', 'maven', 'path', 'url'")
+			continue
+		} else if j, ok := sources[source]; ok {
+			add(path, "duplicate mod file (also $.mods.items[%d])", j)
+			continue
+		}
+		sources[source] = i
+		if el.Name == "" || !(strings.HasPrefix(source, "url:") || strings.HasPrefix(source, "path:")) {
+			continue
+		}
+		// Mods with direct sources have known file names
+		raw := el
+		raw.Checksum = ""
+		if dl, err := mod.New(&raw); err == nil {
+			if j, ok := files[dl.Filename()]; ok {
+				add(path, "duplicate mod file name %s (also $.mods.items[%d])", dl.Filename(), j)
+			} else {
+				files[dl.Filename()] = i
+			}
+		}
+	}
+	if this.Extends == "" {
+		// Extended specs may provide the mods referenced here
+		groups := []string{}
+		for name := range this.Mods.Groups {
+			groups = append(groups, name)
+		}
+		sort.Strings(groups)
+		for _, name := range groups {
+			for i, el := range this.Mods.Groups[name].Mods {
+				if _, ok := names[el]; !ok {
+					add(fmt.Sprintf("$.mods.groups.%s[%d]", name, i), "unknown mod %q", el)
+				}
+			}
+		}
+		if len(this.Mods.Remove) > 0 {
+			add("$.mods.remove", "requires 'extends'")
+		}
+		if len(this.Mods.Unignore) > 0 {
+			add("$.mods.unignore", "requires 'extends'")
+		}
+	}
+	return problems
+}
+
+// modSource returns a key identifying the file source of the mod, or an
+// empty string if it has none. Mods with equal keys share a file.
+func modSource(raw *mod.Raw) string {
+	switch {
+	case raw.Modrinth != "":
+		return "modrinth:" + raw.Modrinth
+	case raw.Curse != "":
+		return "curse:" + raw.Curse
+	case raw.GitHub != "":
+		return strings.Join([]string{"github:" + raw.GitHub, raw.Tag, raw.Asset}, "|")
+	case raw.Maven != "":
+		return "maven:" + raw.Maven + "|" + raw.Repository
+	case raw.Path != "":
+		return "path:" + raw.Path
+	case raw.Url != "":
+		return "url:" + raw.Url
+	}
+	return ""
+}
+
+// checkChecksum returns a message describing the problem with the given
+// "<algorithm>:<hex>" checksum, or an empty string if it is valid.
+func checkChecksum(checksum string) string {
+	if checksum == "" {
+		return ""
+	}
+	split := strings.SplitN(checksum, ":", 2)
+	if len(split) < 2 {
+		split = []string{"sha256", split[0]}
+	}
+	if _, err := net.NewHash(split[0]); err != nil {
+		return fmt.Sprintf("unsupported checksum algorithm %q", split[0])
+	}
+	valid := len(split[1]) == checksumLengths[split[0]]
+	for _, c := range split[1] {
+		valid = valid && strings.ContainsRune("0123456789abcdef", c)
+	}
+	if !valid {
+		return fmt.Sprintf("invalid %s checksum - expected %d lowercase hex digits",
+			split[0], checksumLengths[split[0]])
+	}
+	return ""
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// checkFields reports the keys of the JSON value that do not match a
+// field of the type 't', at the given JSON path. Fields holding
+// interfaces are resolved by m3 rather than read from the spec, and are
+// not matched.
+func checkFields(value interface{}, t reflect.Type, path string, problems *[]Problem) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			// Type mismatches are reported when parsing
+			return
+		}
+		keys := []string{}
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if path == "$" && key == "$schema" {
+				continue
+			}
+			field, ok := findField(t, key)
+			if !ok {
+				*problems = append(*problems, Problem{path + "." + key, "unknown field"})
+				continue
+			}
+			checkFields(object[key], field.Type, path+"."+key, problems)
+		}
+	case reflect.Slice:
+		if list, ok := value.([]interface{}); ok {
+			for i, el := range list {
+				checkFields(el, t.Elem(), fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case reflect.Map:
+		if object, ok := value.(map[string]interface{}); ok {
+			keys := []string{}
+			for key := range object {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				elem := t.Elem()
+				if _, ok := object[key].(map[string]interface{}); !ok &&
+					reflect.PtrTo(elem).Implements(unmarshalerType) {
+					// Alternative forms are checked when parsing
+					continue
+				}
+				checkFields(object[key], elem, path+"."+key, problems)
+			}
+		}
+	}
+}
+
+// findField returns the field of the struct type matching the JSON key,
+// using the case-insensitive matching of encoding/json.
+func findField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Type.Kind() == reflect.Interface ||
+			(field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Interface) {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		} else if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,58 @@
+package spec
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"testing"
+)
+
+// schemaProperties returns the property patterns of the given top level
+// object of the spec schema.
+func schemaProperties(t *testing.T, name string) []*regexp.Regexp {
+	data, err := ioutil.ReadFile("../../modpack.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		PatternProperties map[string]struct {
+			PatternProperties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	for key, el := range schema.PatternProperties {
+		if regexp.MustCompile(key).MatchString(name) {
+			patterns := []*regexp.Regexp{}
+			for key := range el.PatternProperties {
+				patterns = append(patterns, regexp.MustCompile(key))
+			}
+			return patterns
+		}
+	}
+	t.Fatalf("%s not found in the schema", name)
+	return nil
+}
+
+func TestValidateForge(t *testing.T) {
+	patterns := schemaProperties(t, "forge")
+	for key, value := range map[string]string{
+		"version": "1.20.1-47.2.0", "minecraft": "1.20.1", "type": "neoforge",
+	} {
+		data, _ := json.Marshal(map[string]interface{}{"forge": map[string]string{key: value}})
+		inSchema := false
+		for _, el := range patterns {
+			inSchema = inSchema || el.MatchString(key)
+		}
+		problems := []Problem{}
+		for _, el := range Validate(data) {
+			if el.Path == "$.forge."+key {
+				problems = append(problems, el)
+			}
+		}
+		if inSchema != (len(problems) == 0) {
+			t.Errorf("forge %s: schema accepts it: %v, validator reports %v", key, inSchema, problems)
+		}
+	}
+}